@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+)
+
+// GatewayTokenHeader carries the gateway credential on validation calls, whose Authorization header
+// holds the API key being validated
+const GatewayTokenHeader = "X-Gateway-Token"
+
+// AdminAuthMiddleware guards routes with admin tokens presented as "Authorization: Bearer <token>"
+type AdminAuthMiddleware struct {
+	authenticator       AdminAuthenticator
+	requireGatewayToken bool
+}
+
+func NewAdminAuthMiddleware(authenticator AdminAuthenticator, requireGatewayToken bool) AdminAuthMiddleware {
+	return AdminAuthMiddleware{
+		authenticator:       authenticator,
+		requireGatewayToken: requireGatewayToken,
+	}
+}
+
+// RequireRole only lets requests through whose admin token holds role
+func (m AdminAuthMiddleware) RequireRole(role string) mux.MiddlewareFunc {
+	return m.require(role, func(adminToken *domain.AdminToken, _ *http.Request) bool {
+		return adminToken.HasRole(role)
+	})
+}
+
+// RequireOrganizationRole guards routes scoped to the organization in the {orgId} path variable. Besides
+// platform tokens holding role, org_admin tokens of that organization are let through.
+func (m AdminAuthMiddleware) RequireOrganizationRole(role string) mux.MiddlewareFunc {
+	return m.require(role, func(adminToken *domain.AdminToken, r *http.Request) bool {
+		return adminToken.ManagesOrganization(mux.Vars(r)["orgId"], role)
+	})
+}
+
+func (m AdminAuthMiddleware) require(role string, allowed func(adminToken *domain.AdminToken, r *http.Request) bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found {
+				respondWithAuthError(w, domain.ErrUnauthenticated)
+				return
+			}
+			adminToken, err := m.authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				respondWithAuthError(w, err)
+				return
+			}
+			if !allowed(adminToken, r) {
+				respondWithAuthError(w, fmt.Errorf("%w: the %s role is required", domain.ErrPermissionDenied, role))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithActor(r.Context(), adminToken.TokenId)))
+		})
+	}
+}
+
+// RequireGateway guards the validation endpoint with a gateway token in the X-Gateway-Token header,
+// if gateway tokens are required at all
+func (m AdminAuthMiddleware) RequireGateway() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.requireGatewayToken {
+				adminToken, err := m.authenticator.Authenticate(r.Context(), r.Header.Get(GatewayTokenHeader))
+				if err != nil {
+					respondWithAuthError(w, err)
+					return
+				}
+				if !adminToken.HasRole(domain.AdminRoleGateway) {
+					respondWithAuthError(w, fmt.Errorf("%w: the %s role is required", domain.ErrPermissionDenied, domain.AdminRoleGateway))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnauthenticated):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api-key-manager"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, domain.ErrPermissionDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
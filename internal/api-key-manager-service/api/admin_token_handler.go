@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type AdminTokenHandler struct {
+	adminTokenManager AdminTokenManager
+}
+
+func NewAdminTokenHandler(adminTokenManager AdminTokenManager) AdminTokenHandler {
+	return AdminTokenHandler{adminTokenManager: adminTokenManager}
+}
+
+func (a AdminTokenHandler) CreateAdminToken(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to create an admin token")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	request := domain.AdminTokenRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.adminTokenManager.CreateAdminToken(ctx, request)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminTokenResponse(w, resp)
+}
+
+func (a AdminTokenHandler) ListAdminTokens(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to list admin tokens")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	resp, err := a.adminTokenManager.ListAdminTokens(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminTokenResponse(w, resp)
+}
+
+func (a AdminTokenHandler) RevokeAdminToken(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to revoke an admin token")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	tokenId := mux.Vars(r)["tokenId"]
+
+	if err := a.adminTokenManager.RevokeAdminToken(ctx, tokenId); err != nil {
+		if errors.Is(err, domain.ErrAdminTokenNotFound) {
+			respondWithDeletion(w, false, tokenId, "Admin token not found", http.StatusNotFound)
+			return
+		}
+		respondWithDeletion(w, false, tokenId, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithDeletion(w, true, tokenId, "Admin token successfully revoked", http.StatusOK)
+}
+
+func writeAdminTokenResponse(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 	"github.com/gorilla/mux"
 	"net/http"
 	"time"
@@ -38,19 +40,27 @@ func (a ApiKeyDeletionHandler) DeleteApiKey(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Expire the API key
-	if err := a.apiKeyDeleter.ExpireApiKey(ctx, keyId); err != nil {
+	// Expire the API key, recording the optional reason
+	if err := a.apiKeyDeleter.ExpireApiKey(ctx, keyId, r.URL.Query().Get("reason")); err != nil {
 		// Check if it's a not found error
-		if err.Error() == fmt.Sprintf("API key with ID %s not found", keyId) {
+		if errors.Is(err, domain.ErrApiKeyNotFound) {
 			respondWithDeletion(w, false, keyId, "API key not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, domain.ErrInvalidRequest) {
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidStatusTransition) {
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusConflict)
+			return
+		}
 		respondWithDeletion(w, false, keyId, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Return successful deletion response
-	respondWithDeletion(w, true, keyId, "API key successfully expired", http.StatusOK)
+	respondWithDeletion(w, true, keyId, "API key successfully revoked", http.StatusOK)
 }
 
 func respondWithDeletion(w http.ResponseWriter, success bool, apiId, message string, statusCode int) {
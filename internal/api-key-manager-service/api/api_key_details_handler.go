@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeyDetailsHandler struct {
+	apiKeyGetter      ApiKeyGetter
+	apiKeyUpdater     ApiKeyUpdater
+	apiKeyUsageGetter ApiKeyUsageGetter
+}
+
+func NewApiKeyDetailsHandler(apiKeyGetter ApiKeyGetter, apiKeyUpdater ApiKeyUpdater, apiKeyUsageGetter ApiKeyUsageGetter) ApiKeyDetailsHandler {
+	return ApiKeyDetailsHandler{apiKeyGetter: apiKeyGetter, apiKeyUpdater: apiKeyUpdater, apiKeyUsageGetter: apiKeyUsageGetter}
+}
+
+func (a ApiKeyDetailsHandler) GetApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to get an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	apiKey, err := a.apiKeyGetter.GetApiKey(ctx, mux.Vars(r)["keyId"])
+	writeApiKeyResponse(w, apiKey, err)
+}
+
+// UpdateApiKey applies the edit and responds with the key as it is afterwards, including its new version
+func (a ApiKeyDetailsHandler) UpdateApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to update an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+
+	request := domain.ApiKeyUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.apiKeyUpdater.UpdateApiKey(ctx, keyId, request); err != nil {
+		writeApiKeyResponse(w, nil, err)
+		return
+	}
+
+	apiKey, err := a.apiKeyGetter.GetApiKey(ctx, keyId)
+	writeApiKeyResponse(w, apiKey, err)
+}
+
+// GetApiKeyUsage responds with the usage of a key per hour or per day, e.g. ?granularity=hour
+func (a ApiKeyDetailsHandler) GetApiKeyUsage(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to get the usage of an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	usage, err := a.apiKeyUsageGetter.GetApiKeyUsage(ctx, mux.Vars(r)["keyId"], r.URL.Query().Get("granularity"))
+	switch {
+	case errors.Is(err, domain.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrApiKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(usage); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeApiKeyResponse(w http.ResponseWriter, apiKey *domain.ApiKeyWithStats, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrApiKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(apiKey); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 	"net/http"
@@ -31,7 +32,11 @@ func (a ApiKeyGeneratorHandler) ApiKeyGenerator(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	apiId, apiKey, err := a.apiKeyGenerator.GenerateApiKey(ctx, request.OrganizationName)
+	apiId, apiKey, err := a.apiKeyGenerator.GenerateApiKey(ctx, request)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
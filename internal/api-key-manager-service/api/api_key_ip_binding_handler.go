@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeyIPBindingHandler struct {
+	ipBindingResetter ApiKeyIPBindingResetter
+}
+
+func NewApiKeyIPBindingHandler(ipBindingResetter ApiKeyIPBindingResetter) ApiKeyIPBindingHandler {
+	return ApiKeyIPBindingHandler{ipBindingResetter: ipBindingResetter}
+}
+
+func (a ApiKeyIPBindingHandler) ResetIPBinding(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to reset the IP binding of an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+	if keyId == "" {
+		respondWithDeletion(w, false, "", "Missing API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.ipBindingResetter.ResetIPBinding(ctx, keyId); err != nil {
+		if errors.Is(err, domain.ErrApiKeyNotFound) {
+			respondWithDeletion(w, false, keyId, "API key not found", http.StatusNotFound)
+			return
+		}
+		respondWithDeletion(w, false, keyId, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithDeletion(w, true, keyId, "API key IP binding successfully reset", http.StatusOK)
+}
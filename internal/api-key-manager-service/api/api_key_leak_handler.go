@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"net/http"
+	"time"
+)
+
+type ApiKeyLeakHandler struct {
+	apiKeyLeakReporter ApiKeyLeakReporter
+}
+
+func NewApiKeyLeakHandler(apiKeyLeakReporter ApiKeyLeakReporter) ApiKeyLeakHandler {
+	return ApiKeyLeakHandler{apiKeyLeakReporter: apiKeyLeakReporter}
+}
+
+func (a ApiKeyLeakHandler) ReportLeak(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a report of a leaked API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	report := domain.LeakReport{}
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.apiKeyLeakReporter.ReportLeak(ctx, report)
+	switch {
+	case errors.Is(err, domain.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrApiKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
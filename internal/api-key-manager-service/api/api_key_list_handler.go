@@ -3,17 +3,24 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 )
 
 type ApiKeyListHandler struct {
-	apiKeyLister ApiKeyLister
+	apiKeyLister      ApiKeyLister
+	staleApiKeyLister StaleApiKeyLister
 }
 
-func NewApiKeyListHandler(apiKeyLister ApiKeyLister) ApiKeyListHandler {
-	return ApiKeyListHandler{apiKeyLister: apiKeyLister}
+func NewApiKeyListHandler(apiKeyLister ApiKeyLister, staleApiKeyLister StaleApiKeyLister) ApiKeyListHandler {
+	return ApiKeyListHandler{apiKeyLister: apiKeyLister, staleApiKeyLister: staleApiKeyLister}
 }
 
 func (a ApiKeyListHandler) ListApiKeys(w http.ResponseWriter, r *http.Request) {
@@ -22,8 +29,48 @@ func (a ApiKeyListHandler) ListApiKeys(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
 	defer cancel()
 
-	// Get the list of API keys with their usage stats
-	apiKeyList, err := a.apiKeyLister.ListApiKeys(ctx)
+	query, err := parseApiKeyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get a page of API keys with their usage stats
+	apiKeyList, err := a.apiKeyLister.ListApiKeys(ctx, query)
+	writeApiKeyList(w, apiKeyList, err)
+}
+
+// ListStaleApiKeys reports the active keys unused for ?days= days (90 by default). It accepts the same
+// filters, order and paging as ListApiKeys.
+func (a ApiKeyListHandler) ListStaleApiKeys(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to list stale API Keys")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	query, err := parseApiKeyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var days int
+	if value := r.URL.Query().Get("days"); value != "" {
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	apiKeyList, err := a.staleApiKeyLister.ListStaleApiKeys(ctx, days, query)
+	writeApiKeyList(w, apiKeyList, err)
+}
+
+// writeApiKeyList writes a page of a key listing, or the error that prevented it
+func writeApiKeyList(w http.ResponseWriter, apiKeyList *domain.ApiKeyListResponse, err error) {
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -38,3 +85,66 @@ func (a ApiKeyListHandler) ListApiKeys(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// parseApiKeyQuery reads the listing filter, order and page from the query string, e.g.
+// ?environment=live&label=team:payments&sort=last_used&order=desc&limit=20&cursor=...
+func parseApiKeyQuery(r *http.Request) (domain.ApiKeyQuery, error) {
+	values := r.URL.Query()
+	query := domain.ApiKeyQuery{
+		Filter: domain.ApiKeyFilter{
+			OrganizationId: values.Get("organization_id"),
+			Environment:    values.Get("environment"),
+			Status:         values.Get("status"),
+		},
+		SortBy: values.Get("sort"),
+		Order:  values.Get("order"),
+		Cursor: values.Get("cursor"),
+	}
+
+	if expired := values.Get("expired"); expired != "" {
+		isExpired, err := strconv.ParseBool(expired)
+		if err != nil {
+			return query, fmt.Errorf("%w: expired must be true or false", domain.ErrInvalidRequest)
+		}
+		query.Filter.Expired = &isExpired
+	}
+
+	for _, label := range values["label"] {
+		key, value, found := strings.Cut(label, ":")
+		if !found || key == "" {
+			return query, fmt.Errorf("%w: label %q must have the form key:value", domain.ErrInvalidRequest, label)
+		}
+		if query.Filter.Labels == nil {
+			query.Filter.Labels = make(map[string]string)
+		}
+		query.Filter.Labels[key] = value
+	}
+
+	var err error
+	if query.Filter.LastUsedAfter, err = parseTimeParameter(values, "last_used_after"); err != nil {
+		return query, err
+	}
+	if query.Filter.LastUsedBefore, err = parseTimeParameter(values, "last_used_before"); err != nil {
+		return query, err
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("%w: limit must be a positive number", domain.ErrInvalidRequest)
+		}
+	}
+	return query, nil
+}
+
+// parseTimeParameter reads an optional RFC 3339 timestamp from the query string
+func parseTimeParameter(values url.Values, name string) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", domain.ErrInvalidRequest, name)
+	}
+	return &parsed, nil
+}
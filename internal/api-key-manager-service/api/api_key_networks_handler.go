@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeyNetworksHandler struct {
+	networksUpdater ApiKeyNetworksUpdater
+}
+
+func NewApiKeyNetworksHandler(networksUpdater ApiKeyNetworksUpdater) ApiKeyNetworksHandler {
+	return ApiKeyNetworksHandler{networksUpdater: networksUpdater}
+}
+
+func (a ApiKeyNetworksHandler) UpdateNetworks(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to update the networks of an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+
+	request := domain.ApiKeyNetworks{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	networks, err := a.networksUpdater.UpdateNetworks(ctx, keyId, request)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrApiKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(networks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeyPurgeHandler struct {
+	apiKeyPurger ApiKeyPurger
+}
+
+func NewApiKeyPurgeHandler(apiKeyPurger ApiKeyPurger) ApiKeyPurgeHandler {
+	return ApiKeyPurgeHandler{apiKeyPurger: apiKeyPurger}
+}
+
+func (a ApiKeyPurgeHandler) PurgeApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to purge an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	resp, err := a.apiKeyPurger.PurgeApiKey(ctx, mux.Vars(r)["keyId"])
+	writePurgeResponse(w, resp, err)
+}
+
+func (a ApiKeyPurgeHandler) PurgeOrganizationApiKeys(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to purge the API Keys of an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	resp, err := a.apiKeyPurger.PurgeOrganizationApiKeys(ctx, mux.Vars(r)["orgId"])
+	writePurgeResponse(w, resp, err)
+}
+
+func writePurgeResponse(w http.ResponseWriter, resp *domain.PurgeResponse, err error) {
+	switch {
+	case errors.Is(err, domain.ErrApiKeyNotFound), errors.Is(err, domain.ErrOrganizationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
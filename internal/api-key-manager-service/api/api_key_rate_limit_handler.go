@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeyRateLimitHandler struct {
+	rateLimitUpdater ApiKeyRateLimitUpdater
+}
+
+func NewApiKeyRateLimitHandler(rateLimitUpdater ApiKeyRateLimitUpdater) ApiKeyRateLimitHandler {
+	return ApiKeyRateLimitHandler{rateLimitUpdater: rateLimitUpdater}
+}
+
+func (a ApiKeyRateLimitHandler) UpdateRateLimit(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to update the rate limit of an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+
+	// A null body clears the key's own limit
+	var rateLimit *domain.RateLimit
+	if err := json.NewDecoder(r.Body).Decode(&rateLimit); err != nil {
+		respondWithDeletion(w, false, keyId, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.rateLimitUpdater.UpdateRateLimit(ctx, keyId, rateLimit); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidRequest):
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrApiKeyNotFound):
+			respondWithDeletion(w, false, keyId, "API key not found", http.StatusNotFound)
+		default:
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	respondWithDeletion(w, true, keyId, "API key rate limit successfully updated", http.StatusOK)
+}
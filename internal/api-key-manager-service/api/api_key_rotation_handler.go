@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"time"
+)
+
+type ApiKeyRotationHandler struct {
+	apiKeyRotator ApiKeyRotator
+}
+
+func NewApiKeyRotationHandler(apiKeyRotator ApiKeyRotator) ApiKeyRotationHandler {
+	return ApiKeyRotationHandler{apiKeyRotator: apiKeyRotator}
+}
+
+func (a ApiKeyRotationHandler) RotateApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to rotate an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+
+	// The body is optional, an empty one uses the default grace period
+	request := domain.ApiKeyRotationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.apiKeyRotator.RotateApiKey(ctx, keyId, request)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrApiKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domain.ErrApiKeyRotated) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
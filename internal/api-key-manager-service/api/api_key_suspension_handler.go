@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type ApiKeySuspensionHandler struct {
+	apiKeySuspender ApiKeySuspender
+}
+
+func NewApiKeySuspensionHandler(apiKeySuspender ApiKeySuspender) ApiKeySuspensionHandler {
+	return ApiKeySuspensionHandler{apiKeySuspender: apiKeySuspender}
+}
+
+func (a ApiKeySuspensionHandler) SuspendApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to suspend an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+	err := a.apiKeySuspender.SuspendApiKey(ctx, keyId)
+	respondWithStatusChange(w, keyId, "API key successfully suspended", err)
+}
+
+func (a ApiKeySuspensionHandler) ResumeApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to resume an API Key")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	keyId := mux.Vars(r)["keyId"]
+	err := a.apiKeySuspender.ResumeApiKey(ctx, keyId)
+	respondWithStatusChange(w, keyId, "API key successfully resumed", err)
+}
+
+func respondWithStatusChange(w http.ResponseWriter, keyId string, message string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrApiKeyNotFound):
+		respondWithDeletion(w, false, keyId, "API key not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidStatusTransition):
+		respondWithDeletion(w, false, keyId, err.Error(), http.StatusConflict)
+	case err != nil:
+		respondWithDeletion(w, false, keyId, err.Error(), http.StatusInternalServerError)
+	default:
+		respondWithDeletion(w, true, keyId, message, http.StatusOK)
+	}
+}
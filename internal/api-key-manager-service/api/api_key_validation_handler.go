@@ -2,26 +2,76 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	TimestampHeader        = "X-Api-Timestamp"
+	NonceHeader            = "X-Api-Nonce"
+	SignedMethodHeader     = "X-Api-Signed-Method"
+	SignedPathHeader       = "X-Api-Signed-Path"
+	SignedBodySha256Header = "X-Api-Body-Sha256"
+
+	// RequiredScopeParam names a scope the key must have been granted; it may be repeated or comma separated
+	RequiredScopeParam = "required_scope"
+
+	_maxSignedBodyBytes = 1 << 20
+)
+
 type ApiKeyValidationHandler struct {
-	apiKeyValidator ApiKeyValidator
+	apiKeyValidator  ApiKeyValidator
+	clientIPResolver ClientIPResolver
 }
 
+const (
+	ErrorCodeStaleRequest          = "stale_request"
+	ErrorCodeReplayedRequest       = "replayed_request"
+	ErrorCodeNonceCacheFull        = "nonce_cache_full"
+	ErrorCodeNonceLimitReached     = "nonce_limit_reached"
+	ErrorCodeIPNotAllowed          = "ip_not_allowed"
+	ErrorCodeRateLimited           = "rate_limited"
+	ErrorCodeQuotaExceeded         = "quota_exceeded"
+	ErrorCodeInsufficientScope     = "insufficient_scope"
+	ErrorCodeOrganizationSuspended = "organization_suspended"
+	ErrorCodeMalformedApiKey       = "malformed_api_key"
+	ErrorCodeApiKeySuspended       = "api_key_suspended"
+	ErrorCodeApiKeyRevoked         = "api_key_revoked"
+	ErrorCodeApiKeyExpired         = "api_key_expired"
+
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	QuotaExceededHeader      = "X-Quota-Exceeded"
+)
+
 type ApiKeyValidationResponse struct {
-	Valid            bool   `json:"valid"`
-	ApiId            string `json:"api_id,omitempty"`
-	OrganizationName string `json:"organization_name,omitempty"`
-	Message          string `json:"message,omitempty"`
+	Valid            bool     `json:"valid"`
+	ApiId            string   `json:"api_id,omitempty"`
+	OrganizationId   string   `json:"organization_id,omitempty"`
+	OrganizationName string   `json:"organization_name,omitempty"`
+	Environment      string   `json:"environment,omitempty"` // test keys should be routed to sandboxes
+	Message          string   `json:"message,omitempty"`
+	ErrorCode        string   `json:"error_code,omitempty"`
+	Status           string   `json:"status,omitempty"` // status of a key refused for not being active
+	QuotaExceeded    bool     `json:"quota_exceeded,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"` // granted to the key, for downstream authorization
 }
 
-func NewApiKeyValidationHandler(apiKeyValidator ApiKeyValidator) ApiKeyValidationHandler {
-	return ApiKeyValidationHandler{apiKeyValidator: apiKeyValidator}
+func NewApiKeyValidationHandler(apiKeyValidator ApiKeyValidator, clientIPResolver ClientIPResolver) ApiKeyValidationHandler {
+	return ApiKeyValidationHandler{
+		apiKeyValidator:  apiKeyValidator,
+		clientIPResolver: clientIPResolver,
+	}
 }
 
 func (a ApiKeyValidationHandler) ValidateApiKey(w http.ResponseWriter, r *http.Request) {
@@ -37,40 +87,203 @@ func (a ApiKeyValidationHandler) ValidateApiKey(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Expected format: "Bearer <private_key>"
+	// Expected format: "Bearer <private_key>" or "Signature <signature>"
 	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
+	if len(parts) != 2 {
 		respondWithValidation(w, false, "", "", "Invalid Authorization header format", http.StatusUnauthorized)
 		return
 	}
 
-	privateKey := parts[1]
-
 	// Get client IP address
-	ipAddress := r.RemoteAddr
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		ipAddress = forwarded
-	}
+	ipAddress := a.clientIPResolver.ClientIP(r)
+	requiredScopes := parseRequiredScopes(r)
 
 	// Validate the API key
-	apiKey, err := a.apiKeyValidator.ValidateApiKey(ctx, privateKey, ipAddress)
+	var result *domain.ValidationResult
+	var err error
+	switch parts[0] {
+	case "Bearer":
+		result, err = a.apiKeyValidator.ValidateApiKey(ctx, parts[1], ipAddress, requiredScopes)
+	case "Signature":
+		signedRequest, parseErr := parseSignedRequest(r, parts[1])
+		if parseErr != nil {
+			respondWithValidation(w, false, "", "", parseErr.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err = a.apiKeyValidator.ValidateSignedRequest(ctx, signedRequest, ipAddress, requiredScopes)
+	default:
+		respondWithValidation(w, false, "", "", "Invalid Authorization header format", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
-		respondWithValidation(w, false, "", "", err.Error(), http.StatusUnauthorized)
+		respondWithValidationError(w, err)
 		return
 	}
 
 	// Return successful validation response
-	respondWithValidation(w, true, apiKey.ApiId, apiKey.OrganizationName, "API key is valid", http.StatusOK)
+	if result.RateLimit != nil {
+		setRateLimitHeaders(w, *result.RateLimit)
+	}
+	response := ApiKeyValidationResponse{
+		Valid:            true,
+		ApiId:            result.ApiKey.ApiId,
+		OrganizationId:   result.ApiKey.OrganizationId,
+		OrganizationName: result.ApiKey.OrganizationName,
+		Environment:      result.ApiKey.Environment,
+		Message:          "API key is valid",
+		Scopes:           result.ApiKey.Scopes,
+	}
+	if result.QuotaExceeded {
+		// Soft quotas let the request through but tell the caller it is over its allowance
+		w.Header().Set(QuotaExceededHeader, "true")
+		response.QuotaExceeded = true
+		response.Message = "API key is valid but its usage quota is exceeded"
+	}
+	writeValidationResponse(w, response, http.StatusOK)
+}
+
+// parseRequiredScopes collects the scopes named by the required_scope query parameter
+func parseRequiredScopes(r *http.Request) []string {
+	var scopes []string
+	for _, value := range r.URL.Query()[RequiredScopeParam] {
+		for _, scope := range strings.Split(value, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// parseSignedRequest builds the signed request from the validation call itself. Gateways validating on
+// behalf of a client can describe the client's original request with the X-Api-Signed-* headers.
+func parseSignedRequest(r *http.Request, signature string) (domain.SignedRequest, error) {
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return domain.SignedRequest{}, fmt.Errorf("missing or invalid %s header", TimestampHeader)
+	}
+	nonce := r.Header.Get(NonceHeader)
+	if nonce == "" {
+		return domain.SignedRequest{}, fmt.Errorf("missing %s header", NonceHeader)
+	}
+
+	bodySha256 := r.Header.Get(SignedBodySha256Header)
+	if bodySha256 == "" {
+		body, err := io.ReadAll(io.LimitReader(r.Body, _maxSignedBodyBytes))
+		if err != nil {
+			return domain.SignedRequest{}, fmt.Errorf("failed to read request body: %w", err)
+		}
+		digest := sha256.Sum256(body)
+		bodySha256 = hex.EncodeToString(digest[:])
+	}
+
+	method := r.Header.Get(SignedMethodHeader)
+	if method == "" {
+		method = r.Method
+	}
+	path := r.Header.Get(SignedPathHeader)
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	return domain.SignedRequest{
+		Method:     method,
+		Path:       path,
+		Timestamp:  timestamp,
+		Nonce:      nonce,
+		BodySha256: bodySha256,
+		Signature:  signature,
+	}, nil
 }
 
 func respondWithValidation(w http.ResponseWriter, valid bool, apiId, orgName, message string, statusCode int) {
-	response := ApiKeyValidationResponse{
+	writeValidationResponse(w, ApiKeyValidationResponse{
 		Valid:            valid,
 		ApiId:            apiId,
 		OrganizationName: orgName,
 		Message:          message,
+	}, statusCode)
+}
+
+// respondWithValidationError maps a validation failure to its status code and machine readable error code
+func respondWithValidationError(w http.ResponseWriter, err error) {
+	statusCode := http.StatusUnauthorized
+	errorCode := ""
+	switch {
+	case errors.Is(err, domain.ErrMalformedApiKey):
+		errorCode = ErrorCodeMalformedApiKey
+	case errors.Is(err, domain.ErrStaleRequest):
+		errorCode = ErrorCodeStaleRequest
+	case errors.Is(err, domain.ErrReplayedRequest):
+		errorCode = ErrorCodeReplayedRequest
+	case errors.Is(err, domain.ErrNonceCacheFull):
+		// Nonces expire within the allowed time gap, so the request can be retried shortly
+		statusCode = http.StatusServiceUnavailable
+		errorCode = ErrorCodeNonceCacheFull
+		w.Header().Set("Retry-After", "1")
+	case errors.Is(err, domain.ErrNonceLimitReached):
+		statusCode = http.StatusTooManyRequests
+		errorCode = ErrorCodeNonceLimitReached
+		w.Header().Set("Retry-After", "1")
+	case errors.Is(err, domain.ErrIPNotAllowed):
+		statusCode = http.StatusForbidden
+		errorCode = ErrorCodeIPNotAllowed
+	case errors.Is(err, domain.ErrOrganizationSuspended):
+		statusCode = http.StatusForbidden
+		errorCode = ErrorCodeOrganizationSuspended
+	case errors.Is(err, domain.ErrInsufficientScope):
+		statusCode = http.StatusForbidden
+		errorCode = ErrorCodeInsufficientScope
 	}
 
+	var rateLimitErr *domain.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		statusCode = http.StatusTooManyRequests
+		errorCode = ErrorCodeRateLimited
+		setRateLimitHeaders(w, rateLimitErr.Status)
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(rateLimitErr.Status.RetryAfter)))
+	}
+
+	status := ""
+	var statusErr *domain.ApiKeyStatusError
+	if errors.As(err, &statusErr) {
+		status = statusErr.Status
+		if status == domain.ApiKeyStatusSuspended {
+			statusCode = http.StatusForbidden
+		}
+		errorCode = map[string]string{
+			domain.ApiKeyStatusSuspended: ErrorCodeApiKeySuspended,
+			domain.ApiKeyStatusRevoked:   ErrorCodeApiKeyRevoked,
+			domain.ApiKeyStatusExpired:   ErrorCodeApiKeyExpired,
+		}[status]
+	}
+
+	var quotaErr *domain.QuotaError
+	if errors.As(err, &quotaErr) {
+		statusCode = http.StatusTooManyRequests
+		errorCode = ErrorCodeQuotaExceeded
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(time.Until(quotaErr.ResetsAt))))
+	}
+
+	writeValidationResponse(w, ApiKeyValidationResponse{
+		Valid:     false,
+		Message:   err.Error(),
+		ErrorCode: errorCode,
+		Status:    status,
+	}, statusCode)
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, status domain.RateLimitStatus) {
+	w.Header().Set(RateLimitLimitHeader, strconv.Itoa(status.Limit))
+	w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(status.Remaining))
+	w.Header().Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(status.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+func writeValidationResponse(w http.ResponseWriter, response ApiKeyValidationResponse, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	enc := json.NewEncoder(w)
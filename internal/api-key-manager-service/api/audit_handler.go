@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type AuditHandler struct {
+	auditEventLister AuditEventLister
+}
+
+func NewAuditHandler(auditEventLister AuditEventLister) AuditHandler {
+	return AuditHandler{auditEventLister: auditEventLister}
+}
+
+func (a AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to list audit events")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	resp, err := a.auditEventLister.ListAuditEvents(ctx, r.URL.Query().Get("api_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
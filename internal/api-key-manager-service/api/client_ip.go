@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding headers a trusted proxy can be configured to set
+const (
+	ForwardedHeader     = "Forwarded"
+	XForwardedForHeader = "X-Forwarded-For"
+	XRealIPHeader       = "X-Real-IP"
+)
+
+// ClientIPResolver determines the real client address of a request. Only the forwarding header the
+// trusted proxies set is read, as proxies pass other forwarding headers on from the client untouched.
+// It is only believed when the request arrives from a trusted proxy, and is walked right to left so a
+// client cannot spoof its address by prepending entries.
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+	header         string
+}
+
+func NewClientIPResolver(trustedProxies []string, header string) (ClientIPResolver, error) {
+	resolver := ClientIPResolver{}
+	switch http.CanonicalHeaderKey(header) {
+	case ForwardedHeader:
+		resolver.header = ForwardedHeader
+	case http.CanonicalHeaderKey(XForwardedForHeader):
+		resolver.header = XForwardedForHeader
+	case http.CanonicalHeaderKey(XRealIPHeader):
+		resolver.header = XRealIPHeader
+	default:
+		return ClientIPResolver{}, fmt.Errorf("unsupported trusted proxy header %q, must be %s, %s or %s", header, ForwardedHeader, XForwardedForHeader, XRealIPHeader)
+	}
+
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return ClientIPResolver{}, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			addr = addr.Unmap()
+			resolver.trustedProxies = append(resolver.trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return ClientIPResolver{}, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, prefix.Masked())
+	}
+	return resolver, nil
+}
+
+// ClientIP returns the client address of r without a port
+func (c ClientIPResolver) ClientIP(r *http.Request) string {
+	remote, ok := parseHostIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !c.isTrusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	switch c.header {
+	case ForwardedHeader:
+		hops = forwardedFor(r.Header.Values(ForwardedHeader))
+	case XForwardedForHeader:
+		hops = xForwardedFor(r.Header.Values(XForwardedForHeader))
+	case XRealIPHeader:
+		// The proxy sets X-Real-IP rather than appending to it, so it holds a single address
+		if realIP, ok := parseHostIP(r.Header.Get(XRealIPHeader)); ok {
+			return realIP.String()
+		}
+	}
+	if len(hops) == 0 {
+		return remote.String()
+	}
+
+	// Each trusted proxy appended the address it received the request from, so the first untrusted
+	// address from the right is the client
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHostIP(hops[i])
+		if !ok {
+			// Nothing left of an unparseable entry can be attributed to a trusted proxy
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (c ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor extracts the for= parameters of RFC 7239 Forwarded headers in hop order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					hops = append(hops, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// xForwardedFor splits X-Forwarded-For headers into hops, oldest first
+func xForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// parseHostIP parses an address that may carry a port or IPv6 brackets, e.g. "[2001:db8::1]:443"
+func parseHostIP(value string) (netip.Addr, bool) {
+	host := strings.TrimSpace(value)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
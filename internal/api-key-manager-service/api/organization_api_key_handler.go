@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// OrganizationApiKeyHandler serves the self-service key API under /orgs/{orgId}/keys, which only ever
+// touches the keys of the organization in the path
+type OrganizationApiKeyHandler struct {
+	apiKeyGenerator ApiKeyGenerator
+	apiKeyLister    OrganizationApiKeyLister
+	apiKeyDeleter   OrganizationApiKeyDeleter
+}
+
+func NewOrganizationApiKeyHandler(apiKeyGenerator ApiKeyGenerator, apiKeyLister OrganizationApiKeyLister, apiKeyDeleter OrganizationApiKeyDeleter) OrganizationApiKeyHandler {
+	return OrganizationApiKeyHandler{
+		apiKeyGenerator: apiKeyGenerator,
+		apiKeyLister:    apiKeyLister,
+		apiKeyDeleter:   apiKeyDeleter,
+	}
+}
+
+func (o OrganizationApiKeyHandler) GenerateApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to create an API Key for an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	orgId := mux.Vars(r)["orgId"]
+
+	request := domain.ApiKeyGeneratorRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The organization comes from the path, and what a key may do and how much it may be used stay under
+	// the control of platform issuers
+	if (request.OrganizationId != "" && request.OrganizationId != orgId) || request.OrganizationName != "" {
+		http.Error(w, "the organization is taken from the path and cannot be set in the body", http.StatusBadRequest)
+		return
+	}
+	if request.RateLimit != nil || request.Quota != nil || request.Scopes != nil {
+		http.Error(w, "rate_limit, quota and scopes cannot be set through the organization API", http.StatusForbidden)
+		return
+	}
+	request.OrganizationId = orgId
+
+	apiId, apiKey, err := o.apiKeyGenerator.GenerateApiKey(ctx, request)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(domain.ApiKeyGeneratorResponse{ApiId: apiId, ApiKey: apiKey}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (o OrganizationApiKeyHandler) ListApiKeys(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to list the API Keys of an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	query, err := parseApiKeyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	apiKeyList, err := o.apiKeyLister.ListOrganizationApiKeys(ctx, mux.Vars(r)["orgId"], query)
+	if errors.Is(err, domain.ErrInvalidRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(apiKeyList); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (o OrganizationApiKeyHandler) ExpireApiKey(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to expire an API Key of an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	keyId := vars["keyId"]
+
+	reason := r.URL.Query().Get("reason")
+	if err := o.apiKeyDeleter.ExpireOrganizationApiKey(ctx, vars["orgId"], keyId, reason); err != nil {
+		if errors.Is(err, domain.ErrApiKeyNotFound) {
+			respondWithDeletion(w, false, keyId, "API key not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidRequest) {
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidStatusTransition) {
+			respondWithDeletion(w, false, keyId, err.Error(), http.StatusConflict)
+			return
+		}
+		respondWithDeletion(w, false, keyId, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondWithDeletion(w, true, keyId, "API key successfully revoked", http.StatusOK)
+}
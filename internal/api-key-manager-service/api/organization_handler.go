@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type OrganizationHandler struct {
+	organizationManager OrganizationManager
+}
+
+func NewOrganizationHandler(organizationManager OrganizationManager) OrganizationHandler {
+	return OrganizationHandler{organizationManager: organizationManager}
+}
+
+func (o OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to create an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	request := domain.OrganizationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organization, err := o.organizationManager.CreateOrganization(ctx, request)
+	writeOrganizationResponse(w, organization, err)
+}
+
+func (o OrganizationHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to list organizations")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	organizations, err := o.organizationManager.ListOrganizations(ctx)
+	writeOrganizationResponse(w, organizations, err)
+}
+
+func (o OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to get an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	organization, err := o.organizationManager.GetOrganization(ctx, mux.Vars(r)["orgId"])
+	writeOrganizationResponse(w, organization, err)
+}
+
+func (o OrganizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to update an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	request := domain.OrganizationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organization, err := o.organizationManager.UpdateOrganization(ctx, mux.Vars(r)["orgId"], request)
+	writeOrganizationResponse(w, organization, err)
+}
+
+func (o OrganizationHandler) UpdateOrganizationLimits(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to update the limits of an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	limits := domain.OrganizationLimits{}
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organization, err := o.organizationManager.UpdateOrganizationLimits(ctx, mux.Vars(r)["orgId"], limits)
+	writeOrganizationResponse(w, organization, err)
+}
+
+func (o OrganizationHandler) SuspendOrganization(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to suspend an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	organization, err := o.organizationManager.SuspendOrganization(ctx, mux.Vars(r)["orgId"])
+	writeOrganizationResponse(w, organization, err)
+}
+
+func (o OrganizationHandler) ResumeOrganization(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("received a request to resume an organization")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	organization, err := o.organizationManager.ResumeOrganization(ctx, mux.Vars(r)["orgId"])
+	writeOrganizationResponse(w, organization, err)
+}
+
+func writeOrganizationResponse(w http.ResponseWriter, resp any, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrOrganizationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrOrganizationExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	if err := enc.Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -6,17 +6,96 @@ import (
 )
 
 type ApiKeyGenerator interface {
-	GenerateApiKey(ctx context.Context, organizationName string) (string, string, error)
+	GenerateApiKey(ctx context.Context, request domain.ApiKeyGeneratorRequest) (string, string, error)
 }
 
 type ApiKeyValidator interface {
-	ValidateApiKey(ctx context.Context, privateKey string, ipAddress string) (*domain.ApiKey, error)
+	ValidateApiKey(ctx context.Context, key string, ipAddress string, requiredScopes []string) (*domain.ValidationResult, error)
+	ValidateSignedRequest(ctx context.Context, request domain.SignedRequest, ipAddress string, requiredScopes []string) (*domain.ValidationResult, error)
 }
 
 type ApiKeyDeleter interface {
-	ExpireApiKey(_ context.Context, apiId string) error
+	ExpireApiKey(ctx context.Context, apiId string, reason string) error
 }
 
 type ApiKeyLister interface {
-	ListApiKeys(ctx context.Context) (*domain.ApiKeyListResponse, error)
+	ListApiKeys(ctx context.Context, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error)
+}
+
+type StaleApiKeyLister interface {
+	ListStaleApiKeys(ctx context.Context, days int, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error)
+}
+
+type ApiKeyGetter interface {
+	GetApiKey(ctx context.Context, apiId string) (*domain.ApiKeyWithStats, error)
+}
+
+type ApiKeyUsageGetter interface {
+	GetApiKeyUsage(ctx context.Context, apiId string, granularity string) (*domain.ApiKeyUsageResponse, error)
+}
+
+type ApiKeyUpdater interface {
+	UpdateApiKey(ctx context.Context, apiId string, request domain.ApiKeyUpdateRequest) error
+}
+
+type ApiKeyLeakReporter interface {
+	ReportLeak(ctx context.Context, report domain.LeakReport) (*domain.LeakReportResponse, error)
+}
+
+type AuditEventLister interface {
+	ListAuditEvents(ctx context.Context, apiId string) (*domain.AuditEventListResponse, error)
+}
+
+type OrganizationApiKeyLister interface {
+	ListOrganizationApiKeys(ctx context.Context, organizationId string, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error)
+}
+
+type OrganizationApiKeyDeleter interface {
+	ExpireOrganizationApiKey(ctx context.Context, organizationId string, apiId string, reason string) error
+}
+
+type ApiKeyIPBindingResetter interface {
+	ResetIPBinding(ctx context.Context, apiId string) error
+}
+
+type ApiKeyNetworksUpdater interface {
+	UpdateNetworks(ctx context.Context, apiId string, networks domain.ApiKeyNetworks) (*domain.ApiKeyNetworks, error)
+}
+
+type ApiKeyRotator interface {
+	RotateApiKey(ctx context.Context, apiId string, request domain.ApiKeyRotationRequest) (*domain.ApiKeyRotationResponse, error)
+}
+
+type AdminAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.AdminToken, error)
+}
+
+type AdminTokenManager interface {
+	CreateAdminToken(ctx context.Context, request domain.AdminTokenRequest) (*domain.AdminTokenResponse, error)
+	ListAdminTokens(ctx context.Context) (*domain.AdminTokenListResponse, error)
+	RevokeAdminToken(ctx context.Context, tokenId string) error
+}
+
+type OrganizationManager interface {
+	CreateOrganization(ctx context.Context, request domain.OrganizationRequest) (*domain.Organization, error)
+	ListOrganizations(ctx context.Context) (*domain.OrganizationListResponse, error)
+	GetOrganization(ctx context.Context, organizationId string) (*domain.Organization, error)
+	UpdateOrganization(ctx context.Context, organizationId string, request domain.OrganizationRequest) (*domain.Organization, error)
+	UpdateOrganizationLimits(ctx context.Context, organizationId string, limits domain.OrganizationLimits) (*domain.Organization, error)
+	SuspendOrganization(ctx context.Context, organizationId string) (*domain.Organization, error)
+	ResumeOrganization(ctx context.Context, organizationId string) (*domain.Organization, error)
+}
+
+type ApiKeySuspender interface {
+	SuspendApiKey(ctx context.Context, apiId string) error
+	ResumeApiKey(ctx context.Context, apiId string) error
+}
+
+type ApiKeyPurger interface {
+	PurgeApiKey(ctx context.Context, apiId string) (*domain.PurgeResponse, error)
+	PurgeOrganizationApiKeys(ctx context.Context, organizationId string) (*domain.PurgeResponse, error)
+}
+
+type ApiKeyRateLimitUpdater interface {
+	UpdateRateLimit(ctx context.Context, apiId string, rateLimit *domain.RateLimit) error
 }
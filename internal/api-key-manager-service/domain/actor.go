@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor records who is making the request, the ID of the admin token that authenticated it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor recorded by WithActor, or the empty string if there is none
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Roles granted to admin tokens. A token may hold several; AdminRoleAdmin implies every other role.
+const (
+	AdminRoleViewer  = "viewer"  // list keys and their usage
+	AdminRoleIssuer  = "issuer"  // generate, rotate and configure keys
+	AdminRoleRevoker = "revoker" // expire keys
+	AdminRoleGateway = "gateway" // call the validation endpoint when gateway tokens are required
+	AdminRoleAdmin   = "admin"   // everything, including managing admin tokens and purging keys
+	// AdminRoleOrgAdmin manages the keys of the token's own organization through /orgs/{orgId}/keys
+	AdminRoleOrgAdmin = "org_admin"
+)
+
+var AdminRoles = []string{AdminRoleViewer, AdminRoleIssuer, AdminRoleRevoker, AdminRoleGateway, AdminRoleAdmin, AdminRoleOrgAdmin}
+
+// AdminToken is a credential for the key management endpoints. Only the SHA-256 hash of the token is stored.
+type AdminToken struct {
+	TokenId        string     `json:"token_id"`
+	Name           string     `json:"name"`
+	TokenHash      string     `json:"token_hash"`
+	Roles          []string   `json:"roles"`
+	OrganizationId string     `json:"organization_id,omitempty"` // the organization an org_admin token manages
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+}
+
+func (t *AdminToken) HasRole(role string) bool {
+	return slices.Contains(t.Roles, role) || slices.Contains(t.Roles, AdminRoleAdmin)
+}
+
+// ManagesOrganization reports whether the token may manage the keys of organizationId, either as a
+// platform token holding role or as an org_admin token of that organization
+func (t *AdminToken) ManagesOrganization(organizationId string, role string) bool {
+	if t.HasRole(role) {
+		return true
+	}
+	return slices.Contains(t.Roles, AdminRoleOrgAdmin) && t.OrganizationId != "" && t.OrganizationId == organizationId
+}
+
+type AdminTokenRequest struct {
+	Name           string     `json:"name"`
+	Roles          []string   `json:"roles"`
+	OrganizationId string     `json:"organization_id,omitempty"` // required for, and only allowed with, org_admin
+	ExpirationDate *time.Time `json:"expiration_date,omitempty"`
+}
+
+type AdminTokenResponse struct {
+	TokenId        string     `json:"token_id"`
+	Token          string     `json:"token"` // only ever returned at creation
+	Name           string     `json:"name"`
+	Roles          []string   `json:"roles"`
+	OrganizationId string     `json:"organization_id,omitempty"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+}
+
+type AdminTokenSummary struct {
+	TokenId        string     `json:"token_id"`
+	Name           string     `json:"name"`
+	Roles          []string   `json:"roles"`
+	OrganizationId string     `json:"organization_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	IsExpired      bool       `json:"is_expired"`
+}
+
+type AdminTokenListResponse struct {
+	Tokens []AdminTokenSummary `json:"tokens"`
+	Total  int                 `json:"total"`
+}
@@ -1,10 +1,99 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Asymmetric keys hand the private key to the client and only store an address derived from its public
+// key. Opaque keys are random secrets of the form <lookup prefix>.<secret> of which only a peppered hash
+// is stored.
+const (
+	// KeyAlgorithmSecp256k1 keys can also sign requests instead of sending the key
+	KeyAlgorithmSecp256k1 = "secp256k1"
+	KeyAlgorithmEd25519   = "ed25519"
+	KeyAlgorithmP256      = "p256"
+	KeyAlgorithmOpaque    = "opaque"
+)
+
+var KeyAlgorithms = []string{KeyAlgorithmSecp256k1, KeyAlgorithmEd25519, KeyAlgorithmP256, KeyAlgorithmOpaque}
+
+const (
+	EnvironmentLive = "live"
+	// EnvironmentTest keys are for sandboxes; their usage does not count against organization quotas
+	EnvironmentTest = "test"
+)
+
+var Environments = []string{EnvironmentLive, EnvironmentTest}
 
 type ApiKey struct {
-	ApiId            string     `json:"api_id"`
-	PrivateKey       string     `json:"private_key"` // Ideally we store the public key and provide the private key
-	OrganizationName string     `json:"organization_name"`
-	ExpirationDate   *time.Time `json:"expiration_date"`
+	ApiId            string            `json:"api_id"`
+	Version          int64             `json:"version"` // incremented by every change, for optimistic concurrency
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`           // zero for keys issued before it was recorded
+	CreatedBy        string            `json:"created_by,omitempty"` // ID of the admin token that issued the key
+	UpdatedAt        time.Time         `json:"updated_at"`           // last edit, rotation or revocation
+	Status           string            `json:"status"`               // active, suspended or revoked, see StatusAt
+	RevokedAt        *time.Time        `json:"revoked_at,omitempty"`
+	RevokedBy        string            `json:"revoked_by,omitempty"`
+	RevocationReason string            `json:"revocation_reason,omitempty"`
+	KeyAlgorithm     string            `json:"key_algorithm"`
+	Environment      string            `json:"environment"`
+	Address          string            `json:"address,omitempty"`       // Ethereum address for secp256k1, hex public key otherwise
+	LookupPrefix     string            `json:"lookup_prefix,omitempty"` // non-secret part of an opaque key
+	SecretHash       string            `json:"secret_hash,omitempty"`   // HMAC-SHA256 of an opaque key's secret
+	OrganizationId   string            `json:"organization_id,omitempty"`
+	OrganizationName string            `json:"organization_name"` // kept in sync with the organization's name
+	ExpirationDate   *time.Time        `json:"expiration_date"`
+	BoundIP          string            `json:"bound_ip,omitempty"` // only this IP may validate the key when set
+	AllowedCIDRs     []string          `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs      []string          `json:"denied_cidrs,omitempty"`
+	RateLimit        *RateLimit        `json:"rate_limit,omitempty"` // overrides the organization default
+	Quota            *Quota            `json:"quota,omitempty"`
+	Scopes           []string          `json:"scopes,omitempty"`         // e.g. orders:read
+	PredecessorId    string            `json:"predecessor_id,omitempty"` // key this one replaced by rotation
+	SuccessorId      string            `json:"successor_id,omitempty"`   // key that replaced this one by rotation
+}
+
+// UnmarshalJSON reads keys persisted by earlier versions, which kept the address of a secp256k1 key in
+// a field named private_key, named the algorithm key_format, were all live and had no status
+func (k *ApiKey) UnmarshalJSON(data []byte) error {
+	type apiKey ApiKey
+	legacy := struct {
+		*apiKey
+		PrivateKey string `json:"private_key"`
+		KeyFormat  string `json:"key_format"`
+	}{apiKey: (*apiKey)(k)}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	if k.Address == "" {
+		k.Address = legacy.PrivateKey
+	}
+	if k.KeyAlgorithm == "" {
+		k.KeyAlgorithm = KeyAlgorithmSecp256k1
+		if legacy.KeyFormat == KeyAlgorithmOpaque {
+			k.KeyAlgorithm = KeyAlgorithmOpaque
+		}
+	}
+	if k.Environment == "" {
+		k.Environment = EnvironmentLive
+	}
+	if k.Status == "" {
+		k.Status = ApiKeyStatusActive
+		if k.RevokedAt != nil {
+			k.Status = ApiKeyStatusRevoked
+		}
+	}
+	return nil
+}
+
+// Revocation records who withdrew a key ahead of time, and why
+type Revocation struct {
+	RevokedAt time.Time `json:"revoked_at"`
+	RevokedBy string    `json:"revoked_by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
 }
@@ -1,5 +1,21 @@
 package domain
 
+import "time"
+
 type ApiKeyGeneratorRequest struct {
-	OrganizationName string `json:"organization_name"`
+	OrganizationId   string            `json:"organization_id,omitempty"`
+	OrganizationName string            `json:"organization_name,omitempty"` // used to find or create the organization when no ID is given
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	KeyAlgorithm     string            `json:"key_algorithm,omitempty"` // defaults to the organization's, then secp256k1
+	Environment      string            `json:"environment,omitempty"`   // live (default) or test
+	ExpiresAt        *time.Time        `json:"expires_at,omitempty"`
+	TTLSeconds       *int64            `json:"ttl_seconds,omitempty"` // alternative to expires_at, relative to the time of issue
+	BoundIP          string            `json:"bound_ip,omitempty"`
+	AllowedCIDRs     []string          `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs      []string          `json:"denied_cidrs,omitempty"`
+	RateLimit        *RateLimit        `json:"rate_limit,omitempty"`
+	Quota            *Quota            `json:"quota,omitempty"`
+	Scopes           []string          `json:"scopes,omitempty"`
 }
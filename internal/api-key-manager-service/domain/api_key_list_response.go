@@ -2,22 +2,93 @@ package domain
 
 import "time"
 
+// Orders of a key listing. Keys never used sort as the oldest last use.
+const (
+	ApiKeySortCreatedAt     = "created_at"
+	ApiKeySortLastUsed      = "last_used"
+	ApiKeySortTotalRequests = "total_requests"
+)
+
+var ApiKeySorts = []string{ApiKeySortCreatedAt, ApiKeySortLastUsed, ApiKeySortTotalRequests}
+
+const (
+	SortOrderAscending  = "asc"
+	SortOrderDescending = "desc"
+)
+
+// ApiKeyFilter narrows a key listing, empty fields match every key
+type ApiKeyFilter struct {
+	OrganizationId string
+	Environment    string
+	Status         string            // status at the time of the query, see ApiKey.StatusAt
+	Expired        *bool             // whether the expiration date has passed, regardless of the status
+	Labels         map[string]string // keys must carry every one of these labels
+	// LastUsedAfter and LastUsedBefore bound the last accepted validation, and exclude keys never used
+	LastUsedAfter  *time.Time
+	LastUsedBefore *time.Time
+	// UnusedSince matches keys created before it that have not been used since, including never used keys
+	UnusedSince *time.Time
+}
+
+// ApiKeyQuery selects one page of a key listing
+type ApiKeyQuery struct {
+	Filter ApiKeyFilter
+	SortBy string
+	Order  string
+	Limit  int
+	Cursor string // NextCursor of the previous page, empty for the first page
+}
+
+// ApiKeyPage is one page of the keys matching an ApiKeyQuery
+type ApiKeyPage struct {
+	ApiKeys    []*ApiKey
+	Total      int    // keys matching the filter on all pages
+	NextCursor string // empty on the last page
+}
+
 type ApiKeyListResponse struct {
-	ApiKeys []ApiKeyWithStats `json:"api_keys"`
-	Total   int               `json:"total"`
+	ApiKeys    []ApiKeyWithStats `json:"api_keys"`
+	Total      int               `json:"total"` // keys matching the filter on all pages
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 type ApiKeyWithStats struct {
-	ApiId            string     `json:"api_id"`
-	OrganizationName string     `json:"organization_name"`
-	ExpirationDate   *time.Time `json:"expiration_date"`
-	IsExpired        bool       `json:"is_expired"`
-	UsageStats       UsageStats `json:"usage_stats"`
+	ApiId            string            `json:"api_id"`
+	Version          int64             `json:"version"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	CreatedBy        string            `json:"created_by,omitempty"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	Status           string            `json:"status"`
+	RevokedAt        *time.Time        `json:"revoked_at,omitempty"`
+	RevokedBy        string            `json:"revoked_by,omitempty"`
+	RevocationReason string            `json:"revocation_reason,omitempty"`
+	KeyAlgorithm     string            `json:"key_algorithm"`
+	Environment      string            `json:"environment"`
+	LookupPrefix     string            `json:"lookup_prefix,omitempty"`
+	OrganizationId   string            `json:"organization_id,omitempty"`
+	OrganizationName string            `json:"organization_name"`
+	ExpirationDate   *time.Time        `json:"expiration_date"`
+	IsExpired        bool              `json:"is_expired"`
+	BoundIP          string            `json:"bound_ip,omitempty"`
+	AllowedCIDRs     []string          `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs      []string          `json:"denied_cidrs,omitempty"`
+	RateLimit        *RateLimit        `json:"rate_limit,omitempty"`
+	Scopes           []string          `json:"scopes,omitempty"`
+	PredecessorId    string            `json:"predecessor_id,omitempty"`
+	SuccessorId      string            `json:"successor_id,omitempty"`
+	UsageStats       UsageStats        `json:"usage_stats"`
 }
 
 type UsageStats struct {
 	TotalRequests    uint64     `json:"total_requests"`
+	RejectedRequests uint64     `json:"rejected_requests"`
 	LastUsed         *time.Time `json:"last_used,omitempty"`
 	UniqueIPCount    int        `json:"unique_ip_count"`
 	MostRecentIP     string     `json:"most_recent_ip,omitempty"`
-}
\ No newline at end of file
+	// Quota is the key's own quota, OrganizationQuota the quota shared by all keys of its organization
+	Quota             *QuotaStats `json:"quota,omitempty"`
+	OrganizationQuota *QuotaStats `json:"organization_quota,omitempty"`
+}
@@ -0,0 +1,7 @@
+package domain
+
+// ApiKeyNetworks is the CIDR allowlist and denylist of an API key
+type ApiKeyNetworks struct {
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	DeniedCIDRs  []string `json:"denied_cidrs"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+type ApiKeyRotationRequest struct {
+	// GracePeriodSeconds keeps the rotated key valid alongside its successor, defaults to the configured grace period
+	GracePeriodSeconds *int `json:"grace_period_seconds,omitempty"`
+}
+
+type ApiKeyRotationResponse struct {
+	ApiId                     string     `json:"api_id"`
+	ApiKey                    string     `json:"api_key"`
+	PredecessorId             string     `json:"predecessor_id"`
+	PredecessorExpirationDate *time.Time `json:"predecessor_expiration_date"`
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ApiKeyStatusActive = "active"
+	// ApiKeyStatusSuspended keys fail validation until they are resumed
+	ApiKeyStatusSuspended = "suspended"
+	// ApiKeyStatusRevoked keys were withdrawn ahead of time and can never be used again
+	ApiKeyStatusRevoked = "revoked"
+	// ApiKeyStatusExpired keys reached their expiration date. It follows from the date and is never stored.
+	ApiKeyStatusExpired = "expired"
+)
+
+var ApiKeyStatuses = []string{ApiKeyStatusActive, ApiKeyStatusSuspended, ApiKeyStatusRevoked, ApiKeyStatusExpired}
+
+// apiKeyTransitions lists the statuses a key can be moved to from each status. Revoked and expired keys
+// are final.
+var apiKeyTransitions = map[string][]string{
+	ApiKeyStatusActive:    {ApiKeyStatusSuspended, ApiKeyStatusRevoked},
+	ApiKeyStatusSuspended: {ApiKeyStatusActive, ApiKeyStatusRevoked},
+}
+
+// StatusAt is the status of the key at now. A revocation outranks the expiration date, which outranks a
+// suspension.
+func (k *ApiKey) StatusAt(now time.Time) string {
+	if k.Status == ApiKeyStatusRevoked {
+		return ApiKeyStatusRevoked
+	}
+	if k.ExpirationDate != nil && k.ExpirationDate.Before(now) {
+		return ApiKeyStatusExpired
+	}
+	if k.Status == ApiKeyStatusSuspended {
+		return ApiKeyStatusSuspended
+	}
+	return ApiKeyStatusActive
+}
+
+// CheckTransition returns ErrInvalidStatusTransition unless the key can be moved to status at now
+func (k *ApiKey) CheckTransition(status string, now time.Time) error {
+	current := k.StatusAt(now)
+	if !slices.Contains(apiKeyTransitions[current], status) {
+		return fmt.Errorf("%w: API key %s is %s and cannot become %s", ErrInvalidStatusTransition, k.ApiId, current, status)
+	}
+	return nil
+}
+
+// ApiKeyStatusError is returned when a validation is refused because the key is not active
+type ApiKeyStatusError struct {
+	Status string
+}
+
+func (e *ApiKeyStatusError) Error() string {
+	return fmt.Sprintf("API key is %s", e.Status)
+}
+
+func (e *ApiKeyStatusError) Is(target error) bool {
+	return target == ErrApiKeyInactive
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// ApiKeyUpdateRequest edits the mutable attributes of an API key, nil fields are left unchanged
+type ApiKeyUpdateRequest struct {
+	Version     int64             `json:"version"` // the version the edit is based on, a newer key is not overwritten
+	Name        *string           `json:"name,omitempty"`
+	Description *string           `json:"description,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	Scopes      *[]string         `json:"scopes,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"` // replaces all labels, {} removes them
+}
@@ -1,10 +1,91 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/csherida/api-key-manager-service/pkg/hyperloglog"
+)
+
+const (
+	UsageGranularityHour = "hour"
+	UsageGranularityDay  = "day"
+)
+
+var UsageGranularities = []string{UsageGranularityHour, UsageGranularityDay}
 
 type ApiUsage struct {
 	ApiId             string    `json:"api_id"`
 	IpAddress         string    `json:"ip_address"`
 	CumulativeRequest uint64    `json:"cumulative_request"`
 	ValidatedAt       time.Time `json:"validated_at"`
+	RejectionReason   string    `json:"rejection_reason,omitempty"` // set when the validation was refused
+}
+
+// UsageRollup aggregates the validations of a key during one hour or one UTC day. Raw usage records are
+// only kept for a while, the rollups are what usage stats and quotas are computed from.
+type UsageRollup struct {
+	ApiId            string    `json:"api_id"`
+	Granularity      string    `json:"granularity"`
+	PeriodStart      time.Time `json:"period_start"`
+	Requests         uint64    `json:"requests"` // accepted validations
+	RejectedRequests uint64    `json:"rejected_requests"`
+	// UniqueIPs estimates the distinct IP addresses of accepted validations without keeping the addresses
+	UniqueIPs    hyperloglog.Sketch `json:"unique_ips"`
+	LastUsed     *time.Time         `json:"last_used,omitempty"`
+	MostRecentIP string             `json:"most_recent_ip,omitempty"`
+}
+
+// PeriodEnd is the start of the next period
+func (r *UsageRollup) PeriodEnd() time.Time {
+	if r.Granularity == UsageGranularityHour {
+		return r.PeriodStart.Add(time.Hour)
+	}
+	return r.PeriodStart.AddDate(0, 0, 1)
+}
+
+// UsagePeriodStart is the start of the hour or UTC day at is in
+func UsagePeriodStart(granularity string, at time.Time) time.Time {
+	at = at.UTC()
+	if granularity == UsageGranularityHour {
+		return at.Truncate(time.Hour)
+	}
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Add folds a validation into the rollup
+func (r *UsageRollup) Add(usage *ApiUsage) {
+	if usage.RejectionReason != "" {
+		r.RejectedRequests++
+		return
+	}
+	r.Requests++
+	r.UniqueIPs.Add(usage.IpAddress)
+	if r.LastUsed == nil || !usage.ValidatedAt.Before(*r.LastUsed) {
+		validatedAt := usage.ValidatedAt
+		r.LastUsed = &validatedAt
+		r.MostRecentIP = usage.IpAddress
+	}
+}
+
+// Clone returns a copy of the rollup that can be read while the original keeps being updated
+func (r *UsageRollup) Clone() *UsageRollup {
+	clone := *r
+	clone.UniqueIPs = r.UniqueIPs.Clone()
+	return &clone
+}
+
+// ApiKeyUsageResponse lists the rollups of a key, oldest first
+type ApiKeyUsageResponse struct {
+	ApiId       string        `json:"api_id"`
+	Granularity string        `json:"granularity"`
+	Usage       []UsagePeriod `json:"usage"`
+}
+
+type UsagePeriod struct {
+	PeriodStart      time.Time  `json:"period_start"`
+	Requests         uint64     `json:"requests"`
+	RejectedRequests uint64     `json:"rejected_requests"`
+	UniqueIPCount    int        `json:"unique_ip_count"`
+	LastUsed         *time.Time `json:"last_used,omitempty"`
+	MostRecentIP     string     `json:"most_recent_ip,omitempty"`
 }
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+const (
+	// AuditEventApiKeyLeaked records a leaked key being reported and revoked
+	AuditEventApiKeyLeaked = "api_key_leaked"
+	// AuditEventApiKeyPurged is the tombstone of a key deleted together with its usage records
+	AuditEventApiKeyPurged = "api_key_purged"
+)
+
+// AuditEvent is an entry of the append-only audit trail
+type AuditEvent struct {
+	EventId        string            `json:"event_id"`
+	Type           string            `json:"type"`
+	ApiId          string            `json:"api_id,omitempty"`
+	OrganizationId string            `json:"organization_id,omitempty"`
+	Details        map[string]string `json:"details,omitempty"`
+	OccurredAt     time.Time         `json:"occurred_at"`
+}
+
+type AuditEventListResponse struct {
+	Events []*AuditEvent `json:"events"`
+	Total  int           `json:"total"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrApiKeyNotFound          = errors.New("API key not found")
+	ErrStaleRequest            = errors.New("request timestamp is outside the allowed time gap")
+	ErrReplayedRequest         = errors.New("request nonce has already been used")
+	ErrNonceCacheFull          = errors.New("too many signed requests in flight, retry later")
+	ErrNonceLimitReached       = errors.New("too many signed requests in flight for this API key, retry later")
+	ErrIPNotAllowed            = errors.New("API key is not allowed from this IP address")
+	ErrRateLimited             = errors.New("rate limit exceeded")
+	ErrQuotaExceeded           = errors.New("usage quota exceeded")
+	ErrInsufficientScope       = errors.New("API key is missing a required scope")
+	ErrApiKeyRotated           = errors.New("API key has already been rotated")
+	ErrUnauthenticated         = errors.New("missing or invalid admin token")
+	ErrPermissionDenied        = errors.New("permission denied")
+	ErrAdminTokenNotFound      = errors.New("admin token not found")
+	ErrOrganizationNotFound    = errors.New("organization not found")
+	ErrOrganizationExists      = errors.New("an organization with this name already exists")
+	ErrOrganizationSuspended   = errors.New("organization is suspended")
+	ErrMalformedApiKey         = errors.New("malformed API key")
+	ErrVersionConflict         = errors.New("API key was changed by another request")
+	ErrApiKeyInactive          = errors.New("API key is not active")
+	ErrInvalidStatusTransition = errors.New("API key status cannot change this way")
+)
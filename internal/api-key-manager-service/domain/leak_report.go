@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// LeakReport identifies a key found somewhere it should not be, e.g. by a secret scanner
+type LeakReport struct {
+	ApiKey string `json:"api_key"`
+	Source string `json:"source"`        // who found the key, e.g. github_secret_scanning
+	Url    string `json:"url,omitempty"` // where the key was found
+}
+
+type LeakReportResponse struct {
+	ApiId          string     `json:"api_id"`
+	OrganizationId string     `json:"organization_id,omitempty"`
+	Status         string     `json:"status"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	AlreadyExpired bool       `json:"already_expired"` // the key was already revoked or expired before the report
+	Notified       bool       `json:"notified"`        // whether the organization could be notified
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+const (
+	NotificationApiKeyLeaked = "api_key_leaked"
+)
+
+// Notification tells an organization about something that happened to one of its keys
+type Notification struct {
+	Type             string            `json:"type"`
+	OrganizationId   string            `json:"organization_id,omitempty"`
+	OrganizationName string            `json:"organization_name"`
+	ApiId            string            `json:"api_id"`
+	Message          string            `json:"message"`
+	Details          map[string]string `json:"details,omitempty"`
+	OccurredAt       time.Time         `json:"occurred_at"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+const (
+	OrganizationStatusActive = "active"
+	// OrganizationStatusSuspended makes every key of the organization fail validation
+	OrganizationStatusSuspended = "suspended"
+)
+
+type Organization struct {
+	OrganizationId string            `json:"organization_id"`
+	Name           string            `json:"name"` // unique, ignoring case
+	Status         string            `json:"status"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	KeyAlgorithm   string            `json:"key_algorithm,omitempty"` // default for new keys of the organization
+	// MaxKeyLifetimeSeconds caps how long new keys of the organization stay valid, 0 means no limit
+	MaxKeyLifetimeSeconds int64 `json:"max_key_lifetime_seconds,omitempty"`
+	// RateLimit applies to keys of the organization without a rate limit of their own, nil falls back to the
+	// default rate limit
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+	// Quota is shared by all live keys of the organization
+	Quota     *Quota    `json:"quota,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationLimits replaces the rate limit and quota of an organization, nil removes them
+type OrganizationLimits struct {
+	RateLimit *RateLimit `json:"rate_limit"`
+	Quota     *Quota     `json:"quota"`
+}
+
+// OrganizationRequest creates an organization, or updates one where empty fields are left unchanged
+type OrganizationRequest struct {
+	Name         string            `json:"name,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	KeyAlgorithm string            `json:"key_algorithm,omitempty"`
+	// MaxKeyLifetimeSeconds of 0 removes the limit on update
+	MaxKeyLifetimeSeconds *int64 `json:"max_key_lifetime_seconds,omitempty"`
+}
+
+type OrganizationListResponse struct {
+	Organizations []*Organization `json:"organizations"`
+	Total         int             `json:"total"`
+}
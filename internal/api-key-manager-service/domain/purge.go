@@ -0,0 +1,7 @@
+package domain
+
+// PurgeResponse lists the keys deleted by a purge
+type PurgeResponse struct {
+	ApiIds       []string `json:"api_ids"`
+	UsageRecords int      `json:"usage_records"` // usage records deleted with the keys
+}
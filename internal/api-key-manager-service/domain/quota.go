@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// QuotaEnforcementHard rejects validations once the quota is used up
+	QuotaEnforcementHard = "hard"
+	// QuotaEnforcementSoft accepts validations over quota but flags them
+	QuotaEnforcementSoft = "soft"
+)
+
+// Quota caps the number of accepted validations per UTC day and calendar month. Zero limits are not enforced.
+type Quota struct {
+	Daily       uint64 `json:"daily,omitempty"`
+	Monthly     uint64 `json:"monthly,omitempty"`
+	Enforcement string `json:"enforcement,omitempty"` // hard (default) or soft
+}
+
+type QuotaStats struct {
+	Enforcement string      `json:"enforcement"`
+	Daily       *QuotaUsage `json:"daily,omitempty"`
+	Monthly     *QuotaUsage `json:"monthly,omitempty"`
+}
+
+type QuotaUsage struct {
+	Limit     uint64    `json:"limit"`
+	Used      uint64    `json:"used"`
+	Remaining uint64    `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// UsageCounter counts accepted validations since a point in time, of one key or of all live keys of an
+// organization
+type UsageCounter interface {
+	CountApiUsages(apiId string, since time.Time) (uint64, error)
+	CountOrganizationApiUsages(organizationId string, since time.Time) (uint64, error)
+}
+
+// QuotaError is returned when a validation is refused because a hard quota is used up
+type QuotaError struct {
+	Window   string // daily or monthly
+	ResetsAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s usage quota exceeded, resets at %s", e.Window, e.ResetsAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimit caps how often a key may be validated. Zero fields are not enforced.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	RequestsPerMinute int     `json:"requests_per_minute,omitempty"`
+	Burst             int     `json:"burst,omitempty"` // per second bucket size, defaults to RequestsPerSecond
+}
+
+// RateLimitStatus describes the state of the most constrained rate limit window after a validation
+type RateLimitStatus struct {
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // until the window is fully replenished
+	RetryAfter time.Duration // until the next request is allowed, zero when allowed
+}
+
+// RateLimitError is returned when a validation is refused because the key exceeded its rate limit
+type RateLimitError struct {
+	Status RateLimitStatus
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.Status.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SignedRequest describes a request whose canonical form was signed with an API key instead of
+// sending the key itself
+type SignedRequest struct {
+	Method     string
+	Path       string
+	Timestamp  int64  // unix seconds
+	Nonce      string // unique per request within the allowed time gap
+	BodySha256 string // hex encoded SHA-256 of the request body
+	Signature  string // hex encoded 65 byte [R || S || V] secp256k1 signature
+}
+
+// CanonicalString is the message that gets signed: method, path, timestamp, nonce and body hash separated by newlines
+func (s SignedRequest) CanonicalString() string {
+	return strings.Join([]string{
+		strings.ToUpper(s.Method),
+		s.Path,
+		strconv.FormatInt(s.Timestamp, 10),
+		s.Nonce,
+		strings.ToLower(s.BodySha256),
+	}, "\n")
+}
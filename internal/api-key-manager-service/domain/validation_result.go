@@ -0,0 +1,8 @@
+package domain
+
+// ValidationResult is the outcome of a successful API key validation
+type ValidationResult struct {
+	ApiKey        *ApiKey
+	RateLimit     *RateLimitStatus // nil when the key is not rate limited
+	QuotaExceeded bool             // accepted over a soft quota
+}
@@ -0,0 +1,173 @@
+package infra
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// usageSummary is what listings sort and filter keys by, kept up to date as usage is stored
+type usageSummary struct {
+	TotalRequests uint64
+	LastUsed      time.Time
+}
+
+// apiKeyCursor is the position after the last key of a page. It carries the sort value of that key so
+// the next page starts at the right place even if keys were added or removed in between.
+type apiKeyCursor struct {
+	SortBy string    `json:"sort"`
+	Order  string    `json:"order"`
+	Time   time.Time `json:"time,omitempty"`
+	Count  uint64    `json:"count,omitempty"`
+	ApiId  string    `json:"api_id"`
+}
+
+// summarizeUsage folds an accepted validation into the usage summary of its key. Callers must hold ds.mu.
+func (ds *DataStore) summarizeUsage(usage *domain.ApiUsage) {
+	if usage.RejectionReason != "" {
+		return
+	}
+	summary := ds.usageSummaries[usage.ApiId]
+	summary.TotalRequests = max(summary.TotalRequests, usage.CumulativeRequest)
+	if usage.ValidatedAt.After(summary.LastUsed) {
+		summary.LastUsed = usage.ValidatedAt
+	}
+	ds.usageSummaries[usage.ApiId] = summary
+}
+
+// QueryApiKeys returns the page of keys matching query.Filter that follows query.Cursor, in the order of
+// query.SortBy and query.Order with ties broken by API ID. The page is a consistent view taken under a
+// single read lock.
+func (ds *DataStore) QueryApiKeys(query domain.ApiKeyQuery) (*domain.ApiKeyPage, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	now := time.Now()
+	var positions []apiKeyCursor
+	for apiId, apiKey := range ds.apiKeys {
+		summary := ds.usageSummaries[apiId]
+		if matchesApiKeyFilter(apiKey, summary, query.Filter, now) {
+			positions = append(positions, sortPosition(query, apiKey, summary))
+		}
+	}
+	slices.SortFunc(positions, compareSortPositions)
+
+	start := 0
+	if query.Cursor != "" {
+		after, err := decodeApiKeyCursor(query)
+		if err != nil {
+			return nil, err
+		}
+		// The key the cursor points at may have been deleted or have changed its sort value since
+		var found bool
+		start, found = slices.BinarySearchFunc(positions, after, compareSortPositions)
+		if found {
+			start++
+		}
+	}
+
+	// Without a limit the rest of the keys fit on one page
+	limit := query.Limit
+	if limit <= 0 {
+		limit = len(positions)
+	}
+	end := min(start+limit, len(positions))
+	page := &domain.ApiKeyPage{Total: len(positions)}
+	for _, position := range positions[start:end] {
+		page.ApiKeys = append(page.ApiKeys, ds.apiKeys[position.ApiId])
+	}
+	if end < len(positions) {
+		page.NextCursor = encodeApiKeyCursor(positions[end-1])
+	}
+	return page, nil
+}
+
+func matchesApiKeyFilter(apiKey *domain.ApiKey, summary usageSummary, filter domain.ApiKeyFilter, now time.Time) bool {
+	if filter.OrganizationId != "" && apiKey.OrganizationId != filter.OrganizationId {
+		return false
+	}
+	if filter.Environment != "" && apiKey.Environment != filter.Environment {
+		return false
+	}
+	if filter.Status != "" && apiKey.StatusAt(now) != filter.Status {
+		return false
+	}
+	if filter.Expired != nil {
+		expired := apiKey.ExpirationDate != nil && apiKey.ExpirationDate.Before(now)
+		if expired != *filter.Expired {
+			return false
+		}
+	}
+	for key, value := range filter.Labels {
+		if labelValue, exists := apiKey.Labels[key]; !exists || labelValue != value {
+			return false
+		}
+	}
+	if filter.LastUsedAfter != nil || filter.LastUsedBefore != nil {
+		if summary.LastUsed.IsZero() {
+			return false
+		}
+		if filter.LastUsedAfter != nil && summary.LastUsed.Before(*filter.LastUsedAfter) {
+			return false
+		}
+		if filter.LastUsedBefore != nil && summary.LastUsed.After(*filter.LastUsedBefore) {
+			return false
+		}
+	}
+	if filter.UnusedSince != nil {
+		if apiKey.CreatedAt.After(*filter.UnusedSince) || summary.LastUsed.After(*filter.UnusedSince) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortPosition is where a key falls in the order of query
+func sortPosition(query domain.ApiKeyQuery, apiKey *domain.ApiKey, summary usageSummary) apiKeyCursor {
+	position := apiKeyCursor{SortBy: query.SortBy, Order: query.Order, ApiId: apiKey.ApiId}
+	switch query.SortBy {
+	case domain.ApiKeySortLastUsed:
+		position.Time = summary.LastUsed
+	case domain.ApiKeySortTotalRequests:
+		position.Count = summary.TotalRequests
+	default:
+		position.Time = apiKey.CreatedAt
+	}
+	return position
+}
+
+// compareSortPositions orders positions of the same query, reversing the whole order for descending
+// queries so that pages never skip or repeat keys with equal sort values
+func compareSortPositions(a, b apiKeyCursor) int {
+	result := cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.Count, b.Count), cmp.Compare(a.ApiId, b.ApiId))
+	if a.Order == domain.SortOrderDescending {
+		return -result
+	}
+	return result
+}
+
+func encodeApiKeyCursor(position apiKeyCursor) string {
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeApiKeyCursor reads the cursor of query, which must come from a page of the same order
+func decodeApiKeyCursor(query domain.ApiKeyQuery) (apiKeyCursor, error) {
+	var cursor apiKeyCursor
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.ApiId == "" {
+		return apiKeyCursor{}, fmt.Errorf("%w: cursor is malformed", domain.ErrInvalidRequest)
+	}
+	if cursor.SortBy != query.SortBy || cursor.Order != query.Order {
+		return apiKeyCursor{}, fmt.Errorf("%w: cursor belongs to a listing sorted by %s %s", domain.ErrInvalidRequest, cursor.SortBy, cursor.Order)
+	}
+	return cursor, nil
+}
@@ -1,6 +1,10 @@
 package infra
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,16 +14,33 @@ import (
 
 type DataStore struct {
 	mu              sync.RWMutex
-	apiKeys         map[string]*domain.ApiKey     // keyed by ApiId
-	apiKeysByPublic map[string]*domain.ApiKey     // keyed by public key
-	apiUsages       map[string][]*domain.ApiUsage // keyed by ApiId
+	apiKeys         map[string]*domain.ApiKey        // keyed by ApiId
+	apiKeysByAddr   map[string]*domain.ApiKey        // keyed by Address of ecdsa keys
+	apiKeysByPrefix map[string]*domain.ApiKey        // keyed by LookupPrefix of opaque keys
+	apiUsages       map[string][]*domain.ApiUsage    // keyed by ApiId, only as far back as they are retained
+	hourlyUsage     map[string][]*domain.UsageRollup // keyed by ApiId, in period order
+	dailyUsage      map[string][]*domain.UsageRollup // keyed by ApiId, in period order
+	usageSummaries  map[string]usageSummary          // keyed by ApiId, for sorting and filtering listings
+	adminTokens     map[string]*domain.AdminToken    // keyed by TokenId
+	adminTokenHash  map[string]*domain.AdminToken    // keyed by TokenHash
+	organizations   map[string]*domain.Organization  // keyed by OrganizationId
+	orgsByName      map[string]*domain.Organization  // keyed by lower case name
+	auditEvents     []*domain.AuditEvent             // in order of recording
 }
 
 func NewDataStore() *DataStore {
 	return &DataStore{
 		apiKeys:         make(map[string]*domain.ApiKey),
-		apiKeysByPublic: make(map[string]*domain.ApiKey),
+		apiKeysByAddr:   make(map[string]*domain.ApiKey),
+		apiKeysByPrefix: make(map[string]*domain.ApiKey),
 		apiUsages:       make(map[string][]*domain.ApiUsage),
+		hourlyUsage:     make(map[string][]*domain.UsageRollup),
+		dailyUsage:      make(map[string][]*domain.UsageRollup),
+		usageSummaries:  make(map[string]usageSummary),
+		adminTokens:     make(map[string]*domain.AdminToken),
+		adminTokenHash:  make(map[string]*domain.AdminToken),
+		organizations:   make(map[string]*domain.Organization),
+		orgsByName:      make(map[string]*domain.Organization),
 	}
 }
 
@@ -27,12 +48,26 @@ func NewDataStore() *DataStore {
 func (ds *DataStore) StoreApiKey(apiKey *domain.ApiKey) error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
+	ds.putApiKey(apiKey)
+	return nil
+}
+
+// putApiKey stores an API key under its ID and its lookup index. Updates store a modified copy rather than mutating
+// the stored key, so keys handed out to readers never change underneath them, and get the next version of the key.
+// Callers must hold ds.mu.
+func (ds *DataStore) putApiKey(apiKey *domain.ApiKey) {
+	if existing, exists := ds.apiKeys[apiKey.ApiId]; exists {
+		apiKey.Version = existing.Version + 1
+	} else if apiKey.Version == 0 {
+		apiKey.Version = 1
+	}
 	ds.apiKeys[apiKey.ApiId] = apiKey
-	// Also store by public key for fast lookup
-	if apiKey.PrivateKey != "" {
-		ds.apiKeysByPublic[apiKey.PrivateKey] = apiKey
+	if apiKey.Address != "" {
+		ds.apiKeysByAddr[apiKey.Address] = apiKey
+	}
+	if apiKey.LookupPrefix != "" {
+		ds.apiKeysByPrefix[apiKey.LookupPrefix] = apiKey
 	}
-	return nil
 }
 
 // GetApiKey retrieves an API key by ID
@@ -43,12 +78,24 @@ func (ds *DataStore) GetApiKey(apiId string) (*domain.ApiKey, bool, error) {
 	return apiKey, exists, nil
 }
 
-// GetApiKeyByPublicKey retrieves an API key by its public key (stored in PrivateKey field)
-func (ds *DataStore) GetApiKeyByPublicKey(publicKey string) (*domain.ApiKey, error) {
+// GetApiKeyByAddress retrieves an ecdsa API key by the address derived from it
+func (ds *DataStore) GetApiKeyByAddress(address string) (*domain.ApiKey, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
 
-	apiKey, exists := ds.apiKeysByPublic[publicKey]
+	apiKey, exists := ds.apiKeysByAddr[address]
+	if !exists {
+		return nil, nil
+	}
+	return apiKey, nil
+}
+
+// GetApiKeyByLookupPrefix retrieves an opaque API key by the non-secret prefix of the key
+func (ds *DataStore) GetApiKeyByLookupPrefix(lookupPrefix string) (*domain.ApiKey, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	apiKey, exists := ds.apiKeysByPrefix[lookupPrefix]
 	if !exists {
 		return nil, nil
 	}
@@ -68,7 +115,7 @@ func (ds *DataStore) GetAllApiKeys() ([]*domain.ApiKey, error) {
 	return allKeys, nil
 }
 
-// GetAllActiveApiKeys returns all API keys that haven't expired yet or nil if none exist
+// GetAllActiveApiKeys returns all API keys that haven't expired or been revoked yet or nil if none exist
 func (ds *DataStore) GetAllActiveApiKeys() ([]*domain.ApiKey, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
@@ -77,8 +124,8 @@ func (ds *DataStore) GetAllActiveApiKeys() ([]*domain.ApiKey, error) {
 	var activeKeys []*domain.ApiKey
 
 	for _, apiKey := range ds.apiKeys {
-		// Check if the key hasn't expired
-		if apiKey.ExpirationDate == nil || apiKey.ExpirationDate.After(now) {
+		// Check if the key hasn't expired or been revoked, suspended keys can still be resumed
+		if status := apiKey.StatusAt(now); status == domain.ApiKeyStatusActive || status == domain.ApiKeyStatusSuspended {
 			activeKeys = append(activeKeys, apiKey)
 		}
 	}
@@ -101,31 +148,308 @@ func (ds *DataStore) ExpireApiKey(apiId string, expirationDate *time.Time) error
 	}
 
 	// Update the expiration date
-	apiKey.ExpirationDate = expirationDate
+	updated := *apiKey
+	updated.ExpirationDate = expirationDate
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// UpdateApiKey replaces an API key unless it changed since version, in which case it returns
+// domain.ErrVersionConflict
+func (ds *DataStore) UpdateApiKey(apiKey *domain.ApiKey, version int64) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.checkApiKeyVersion(apiKey.ApiId, version); err != nil {
+		return err
+	}
+
+	updated := *apiKey
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// CheckApiKeyVersion returns domain.ErrVersionConflict if the API key is no longer at version
+func (ds *DataStore) CheckApiKeyVersion(apiId string, version int64) error {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.checkApiKeyVersion(apiId, version)
+}
+
+// checkApiKeyVersion is CheckApiKeyVersion for callers holding ds.mu
+func (ds *DataStore) checkApiKeyVersion(apiId string, version int64) error {
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+	if apiKey.Version != version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", domain.ErrVersionConflict, apiId, apiKey.Version, version)
+	}
+	return nil
+}
+
+// RevokeApiKey permanently revokes an API key and records who revoked it and why. Its expiration date is
+// left as it was. Keys that are already revoked or expired return domain.ErrInvalidStatusTransition.
+func (ds *DataStore) RevokeApiKey(apiId string, revocation domain.Revocation) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil // Key doesn't exist, nothing to revoke
+	}
+	if err := apiKey.CheckTransition(domain.ApiKeyStatusRevoked, revocation.RevokedAt); err != nil {
+		return err
+	}
+
+	updated := *apiKey
+	updated.Status = domain.ApiKeyStatusRevoked
+	updated.RevokedAt = &revocation.RevokedAt
+	updated.RevokedBy = revocation.RevokedBy
+	updated.RevocationReason = revocation.Reason
+	updated.UpdatedAt = revocation.RevokedAt
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// SetApiKeyStatus suspends or resumes an API key, returning domain.ErrInvalidStatusTransition if the key
+// cannot be moved to status at updatedAt
+func (ds *DataStore) SetApiKeyStatus(apiId string, status string, updatedAt time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil // Key doesn't exist, nothing to change
+	}
+	if err := apiKey.CheckTransition(status, updatedAt); err != nil {
+		return err
+	}
+
+	updated := *apiKey
+	updated.Status = status
+	updated.UpdatedAt = updatedAt
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// CheckApiKeyTransition returns domain.ErrInvalidStatusTransition if the API key cannot be moved to status at now
+func (ds *DataStore) CheckApiKeyTransition(apiId string, status string, now time.Time) error {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil
+	}
+	return apiKey.CheckTransition(status, now)
+}
+
+// PurgeApiKeys deletes API keys together with their lookup index entries, usage records and rollups.
+// Unknown keys are skipped.
+func (ds *DataStore) PurgeApiKeys(apiIds []string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for _, apiId := range apiIds {
+		apiKey, exists := ds.apiKeys[apiId]
+		if !exists {
+			continue
+		}
+		// A key rotated in from the same address or prefix may own the index entry by now
+		if ds.apiKeysByAddr[apiKey.Address] == apiKey {
+			delete(ds.apiKeysByAddr, apiKey.Address)
+		}
+		if ds.apiKeysByPrefix[apiKey.LookupPrefix] == apiKey {
+			delete(ds.apiKeysByPrefix, apiKey.LookupPrefix)
+		}
+		delete(ds.apiKeys, apiId)
+		delete(ds.apiUsages, apiId)
+		delete(ds.hourlyUsage, apiId)
+		delete(ds.dailyUsage, apiId)
+		delete(ds.usageSummaries, apiId)
+	}
+	return nil
+}
+
+// BindApiKeyIP binds an API key to ipAddress unless it is already bound, and returns the IP the key is bound to
+func (ds *DataStore) BindApiKeyIP(apiId string, ipAddress string) (string, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return "", nil // Key doesn't exist, nothing to bind
+	}
+
+	if apiKey.BoundIP != "" {
+		return apiKey.BoundIP, nil
+	}
+
+	updated := *apiKey
+	updated.BoundIP = ipAddress
+	ds.putApiKey(&updated)
+
+	return ipAddress, nil
+}
+
+// ResetApiKeyIPBinding removes the IP binding of an API key
+func (ds *DataStore) ResetApiKeyIPBinding(apiId string, updatedAt time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil // Key doesn't exist, nothing to reset
+	}
+
+	updated := *apiKey
+	updated.BoundIP = ""
+	updated.UpdatedAt = updatedAt
+	ds.putApiKey(&updated)
 
 	return nil
 }
 
-// StoreApiUsage stores API usage data with auto-incremented CumulativeRequest
+// UpdateApiKeyNetworks replaces the allowed and denied networks of an API key
+func (ds *DataStore) UpdateApiKeyNetworks(apiId string, allowedCIDRs []string, deniedCIDRs []string, updatedAt time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil // Key doesn't exist, nothing to update
+	}
+
+	updated := *apiKey
+	updated.AllowedCIDRs = allowedCIDRs
+	updated.DeniedCIDRs = deniedCIDRs
+	updated.UpdatedAt = updatedAt
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// UpdateApiKeyRateLimit replaces the rate limit of an API key, nil falls back to the defaults
+func (ds *DataStore) UpdateApiKeyRateLimit(apiId string, rateLimit *domain.RateLimit, updatedAt time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil // Key doesn't exist, nothing to update
+	}
+
+	updated := *apiKey
+	updated.RateLimit = rateLimit
+	updated.UpdatedAt = updatedAt
+	ds.putApiKey(&updated)
+
+	return nil
+}
+
+// CheckApiKeyRotation returns domain.ErrApiKeyNotFound if the API key does not exist and
+// domain.ErrApiKeyRotated if it already has a successor
+func (ds *DataStore) CheckApiKeyRotation(apiId string) error {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	_, err := ds.checkApiKeyRotation(apiId)
+	return err
+}
+
+// checkApiKeyRotation is CheckApiKeyRotation for callers holding ds.mu, returning the key on success
+func (ds *DataStore) checkApiKeyRotation(apiId string) (*domain.ApiKey, error) {
+	apiKey, exists := ds.apiKeys[apiId]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+	if apiKey.SuccessorId != "" {
+		return nil, fmt.Errorf("%w: %s was replaced by %s", domain.ErrApiKeyRotated, apiId, apiKey.SuccessorId)
+	}
+	return apiKey, nil
+}
+
+// RotateApiKey stores successor and links it to the API key it replaces, which expires at expirationDate.
+// A key can only be rotated once, concurrent rotations of the same key return domain.ErrApiKeyRotated.
+func (ds *DataStore) RotateApiKey(apiId string, successor *domain.ApiKey, expirationDate *time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	apiKey, err := ds.checkApiKeyRotation(apiId)
+	if err != nil {
+		return err
+	}
+
+	updated := *apiKey
+	updated.SuccessorId = successor.ApiId
+	updated.ExpirationDate = expirationDate
+	updated.UpdatedAt = successor.CreatedAt
+	ds.putApiKey(&updated)
+	ds.putApiKey(successor)
+
+	return nil
+}
+
+// StoreApiUsage stores API usage data with auto-incremented CumulativeRequest and folds it into the
+// hourly and daily rollups of the key. Rejected validations are recorded with the current count without
+// incrementing it.
 func (ds *DataStore) StoreApiUsage(usage *domain.ApiUsage) error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
+	ds.storeApiUsage(usage)
+	return nil
+}
 
-	// Find the highest CumulativeRequest for this API ID using lo.MaxBy
-	existingUsages := ds.apiUsages[usage.ApiId]
-	if len(existingUsages) > 0 {
-		maxUsage := lo.MaxBy(existingUsages, func(a, b *domain.ApiUsage) bool {
-			return a.CumulativeRequest > b.CumulativeRequest
-		})
-		usage.CumulativeRequest = maxUsage.CumulativeRequest + 1
-	} else {
-		usage.CumulativeRequest = 1
+// storeApiUsage stores usage and rolls it up. Callers must hold ds.mu.
+func (ds *DataStore) storeApiUsage(usage *domain.ApiUsage) {
+	// The summary keeps the count of the key, which outlives the raw records
+	usage.CumulativeRequest = ds.usageSummaries[usage.ApiId].TotalRequests
+	if usage.RejectionReason == "" {
+		usage.CumulativeRequest++
 	}
 
 	ds.apiUsages[usage.ApiId] = append(ds.apiUsages[usage.ApiId], usage)
+	ds.rollUpUsage(usage)
+	ds.summarizeUsage(usage)
+}
+
+// ConsumeQuota stores usage unless check, which counts the usage stored so far, refuses it. Both run
+// under one lock, so concurrent validations cannot all pass a quota with room for only one of them.
+func (ds *DataStore) ConsumeQuota(usage *domain.ApiUsage, check func(counter domain.UsageCounter) error) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := check(heldUsageCounter{ds: ds}); err != nil {
+		return err
+	}
+	ds.storeApiUsage(usage)
 	return nil
 }
 
+// heldUsageCounter counts usage while its caller holds ds.mu
+type heldUsageCounter struct {
+	ds *DataStore
+}
+
+func (c heldUsageCounter) CountApiUsages(apiId string, since time.Time) (uint64, error) {
+	return c.ds.countAcceptedSince(apiId, since), nil
+}
+
+func (c heldUsageCounter) CountOrganizationApiUsages(organizationId string, since time.Time) (uint64, error) {
+	return c.ds.countOrganizationAcceptedSince(organizationId, since), nil
+}
+
+// GetApiUsages returns the usage records of one API key
+func (ds *DataStore) GetApiUsages(apiId string) ([]*domain.ApiUsage, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return slices.Clone(ds.apiUsages[apiId]), nil
+}
+
 // GetAllApiUsages returns all API usage records
 func (ds *DataStore) GetAllApiUsages() (map[string][]*domain.ApiUsage, error) {
 	ds.mu.RLock()
@@ -141,3 +465,217 @@ func (ds *DataStore) GetAllApiUsages() (map[string][]*domain.ApiUsage, error) {
 
 	return result, nil
 }
+
+// CountApiUsages counts the accepted validations of an API key since the given time
+func (ds *DataStore) CountApiUsages(apiId string, since time.Time) (uint64, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.countAcceptedSince(apiId, since), nil
+}
+
+// CountOrganizationApiUsages counts the accepted validations of all live API keys of an organization since the given time
+func (ds *DataStore) CountOrganizationApiUsages(organizationId string, since time.Time) (uint64, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.countOrganizationAcceptedSince(organizationId, since), nil
+}
+
+// countOrganizationAcceptedSince counts the accepted validations of the live keys of an organization from
+// since on. Callers must hold ds.mu.
+func (ds *DataStore) countOrganizationAcceptedSince(organizationId string, since time.Time) uint64 {
+	var count uint64
+	for apiId, apiKey := range ds.apiKeys {
+		if apiKey.OrganizationId == organizationId && apiKey.Environment != domain.EnvironmentTest {
+			count += ds.countAcceptedSince(apiId, since)
+		}
+	}
+	return count
+}
+
+// StoreAdminToken stores an admin token in the data store
+func (ds *DataStore) StoreAdminToken(adminToken *domain.AdminToken) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.putAdminToken(adminToken)
+	return nil
+}
+
+// putAdminToken stores an admin token under both indexes. Callers must hold ds.mu.
+func (ds *DataStore) putAdminToken(adminToken *domain.AdminToken) {
+	ds.adminTokens[adminToken.TokenId] = adminToken
+	ds.adminTokenHash[adminToken.TokenHash] = adminToken
+}
+
+// GetAdminToken retrieves an admin token by ID
+func (ds *DataStore) GetAdminToken(tokenId string) (*domain.AdminToken, bool, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	adminToken, exists := ds.adminTokens[tokenId]
+	return adminToken, exists, nil
+}
+
+// GetAdminTokenByHash retrieves an admin token by the SHA-256 hash of the token, or nil if there is none
+func (ds *DataStore) GetAdminTokenByHash(tokenHash string) (*domain.AdminToken, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.adminTokenHash[tokenHash], nil
+}
+
+// GetAllAdminTokens returns all admin tokens regardless of expiration status
+func (ds *DataStore) GetAllAdminTokens() ([]*domain.AdminToken, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return lo.Values(ds.adminTokens), nil
+}
+
+// ExpireAdminToken sets the expiration date of an admin token to the specified time
+func (ds *DataStore) ExpireAdminToken(tokenId string, expirationDate *time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	adminToken, exists := ds.adminTokens[tokenId]
+	if !exists {
+		return nil // Token doesn't exist, nothing to expire
+	}
+
+	updated := *adminToken
+	updated.ExpirationDate = expirationDate
+	ds.putAdminToken(&updated)
+
+	return nil
+}
+
+// StoreOrganization creates or replaces an organization. Organization names are unique ignoring case,
+// and renaming an organization renames it on all of its keys.
+func (ds *DataStore) StoreOrganization(organization *domain.Organization) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.checkOrganizationName(organization); err != nil {
+		return err
+	}
+
+	if existing, exists := ds.organizations[organization.OrganizationId]; exists && existing.Name != organization.Name {
+		delete(ds.orgsByName, strings.ToLower(existing.Name))
+		for _, apiKey := range ds.apiKeys {
+			if apiKey.OrganizationId == organization.OrganizationId {
+				updated := *apiKey
+				updated.OrganizationName = organization.Name
+				ds.putApiKey(&updated)
+			}
+		}
+	}
+	ds.putOrganization(organization)
+
+	return nil
+}
+
+// CheckOrganizationName returns domain.ErrOrganizationExists if another organization already has the name
+// of organization
+func (ds *DataStore) CheckOrganizationName(organization *domain.Organization) error {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.checkOrganizationName(organization)
+}
+
+// checkOrganizationName is CheckOrganizationName for callers holding ds.mu
+func (ds *DataStore) checkOrganizationName(organization *domain.Organization) error {
+	if other, exists := ds.orgsByName[strings.ToLower(organization.Name)]; exists && other.OrganizationId != organization.OrganizationId {
+		return fmt.Errorf("%w: %s", domain.ErrOrganizationExists, organization.Name)
+	}
+	return nil
+}
+
+// putOrganization stores an organization under both indexes. Callers must hold ds.mu.
+func (ds *DataStore) putOrganization(organization *domain.Organization) {
+	ds.organizations[organization.OrganizationId] = organization
+	ds.orgsByName[strings.ToLower(organization.Name)] = organization
+}
+
+// GetOrganization retrieves an organization by ID
+func (ds *DataStore) GetOrganization(organizationId string) (*domain.Organization, bool, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	organization, exists := ds.organizations[organizationId]
+	return organization, exists, nil
+}
+
+// GetOrganizationByName retrieves an organization by name ignoring case, or nil if there is none
+func (ds *DataStore) GetOrganizationByName(name string) (*domain.Organization, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.orgsByName[strings.ToLower(name)], nil
+}
+
+// GetAllOrganizations returns all organizations regardless of status
+func (ds *DataStore) GetAllOrganizations() ([]*domain.Organization, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return lo.Values(ds.organizations), nil
+}
+
+// StoreAuditEvent appends an event to the audit trail
+func (ds *DataStore) StoreAuditEvent(event *domain.AuditEvent) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.auditEvents = append(ds.auditEvents, event)
+	return nil
+}
+
+// GetAllAuditEvents returns the audit trail in order of recording
+func (ds *DataStore) GetAllAuditEvents() ([]*domain.AuditEvent, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return slices.Clone(ds.auditEvents), nil
+}
+
+// restore replaces the contents of the data store with a snapshot, rebuilding the indexes
+func (ds *DataStore) restore(snapshot fileStoreSnapshot) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.apiKeys = make(map[string]*domain.ApiKey, len(snapshot.ApiKeys))
+	ds.apiKeysByAddr = make(map[string]*domain.ApiKey, len(snapshot.ApiKeys))
+	ds.apiKeysByPrefix = make(map[string]*domain.ApiKey, len(snapshot.ApiKeys))
+	for _, apiKey := range snapshot.ApiKeys {
+		ds.putApiKey(apiKey)
+	}
+
+	ds.apiUsages = make(map[string][]*domain.ApiUsage, len(snapshot.ApiUsages))
+	ds.hourlyUsage = make(map[string][]*domain.UsageRollup, len(snapshot.HourlyUsage))
+	ds.dailyUsage = make(map[string][]*domain.UsageRollup, len(snapshot.DailyUsage))
+	for apiId, usages := range snapshot.ApiUsages {
+		ds.apiUsages[apiId] = usages
+	}
+	if snapshot.DailyUsage == nil {
+		// Snapshots from before rollups still have every usage record to build them from
+		for _, usages := range snapshot.ApiUsages {
+			for _, usage := range usages {
+				ds.rollUpUsage(usage)
+			}
+		}
+	} else {
+		maps.Copy(ds.hourlyUsage, snapshot.HourlyUsage)
+		maps.Copy(ds.dailyUsage, snapshot.DailyUsage)
+	}
+	ds.usageSummaries = make(map[string]usageSummary, len(ds.dailyUsage))
+	for apiId, rollups := range ds.dailyUsage {
+		ds.usageSummaries[apiId] = summarizeRollups(rollups)
+	}
+
+	ds.adminTokens = make(map[string]*domain.AdminToken, len(snapshot.AdminTokens))
+	ds.adminTokenHash = make(map[string]*domain.AdminToken, len(snapshot.AdminTokens))
+	for _, adminToken := range snapshot.AdminTokens {
+		ds.putAdminToken(adminToken)
+	}
+
+	ds.organizations = make(map[string]*domain.Organization, len(snapshot.Organizations))
+	ds.orgsByName = make(map[string]*domain.Organization, len(snapshot.Organizations))
+	for _, organization := range snapshot.Organizations {
+		ds.putOrganization(organization)
+	}
+
+	ds.auditEvents = slices.Clone(snapshot.AuditEvents)
+}
@@ -0,0 +1,855 @@
+package infra
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+const (
+	_walFileName      = "wal.log"
+	_snapshotFileName = "snapshot.json"
+)
+
+type walOp string
+
+const (
+	opStoreApiKey       walOp = "store_api_key"
+	opExpireApiKey      walOp = "expire_api_key"
+	opStoreApiUsage     walOp = "store_api_usage"
+	opBindApiKeyIP      walOp = "bind_api_key_ip"
+	opResetApiKeyIP     walOp = "reset_api_key_ip"
+	opUpdateNetworks    walOp = "update_api_key_networks"
+	opUpdateRateLimit   walOp = "update_api_key_rate_limit"
+	opRotateApiKey      walOp = "rotate_api_key"
+	opStoreAdminToken   walOp = "store_admin_token"
+	opExpireAdminToken  walOp = "expire_admin_token"
+	opStoreOrganization walOp = "store_organization"
+	opStoreAuditEvent   walOp = "store_audit_event"
+	opUpdateApiKey      walOp = "update_api_key"
+	opRevokeApiKey      walOp = "revoke_api_key"
+	opSetApiKeyStatus   walOp = "set_api_key_status"
+	opPurgeApiKeys      walOp = "purge_api_keys"
+	opPruneApiUsages    walOp = "prune_api_usages"
+)
+
+var ErrStoreClosed = errors.New("file store is closed")
+
+type FileStoreOptions struct {
+	Directory            string
+	SnapshotInterval     time.Duration
+	SnapshotEveryRecords int
+}
+
+// FileStore is a durable Repository. Every mutation is appended to a write-ahead log and fsynced
+// before being applied to an in-memory DataStore; the full state is periodically written to a
+// snapshot so the log can be truncated. On startup the latest snapshot is loaded and the log replayed.
+type FileStore struct {
+	mu                   sync.Mutex // serialises log appends so the log order matches the apply order
+	mem                  *DataStore
+	opts                 FileStoreOptions
+	wal                  *os.File
+	seq                  uint64 // sequence number of the last record written or replayed
+	recordsSinceSnapshot int
+	closed               bool
+}
+
+type walRecord struct {
+	Seq      uint64          `json:"seq"`
+	Op       walOp           `json:"op"`
+	Data     json.RawMessage `json:"data"`
+	Checksum uint32          `json:"crc"`
+}
+
+type expireApiKeyRecord struct {
+	ApiId          string     `json:"api_id"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+}
+
+type revokeApiKeyRecord struct {
+	ApiId      string            `json:"api_id"`
+	Revocation domain.Revocation `json:"revocation"`
+}
+
+type setApiKeyStatusRecord struct {
+	ApiId     string    `json:"api_id"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type purgeApiKeysRecord struct {
+	ApiIds []string `json:"api_ids"`
+}
+
+type pruneApiUsagesRecord struct {
+	RawBefore    time.Time `json:"raw_before"`
+	HourlyBefore time.Time `json:"hourly_before"`
+}
+
+type bindApiKeyIPRecord struct {
+	ApiId     string `json:"api_id"`
+	IpAddress string `json:"ip_address,omitempty"`
+}
+
+type resetApiKeyIPRecord struct {
+	ApiId     string    `json:"api_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type updateNetworksRecord struct {
+	ApiId        string    `json:"api_id"`
+	AllowedCIDRs []string  `json:"allowed_cidrs"`
+	DeniedCIDRs  []string  `json:"denied_cidrs"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type updateRateLimitRecord struct {
+	ApiId     string            `json:"api_id"`
+	RateLimit *domain.RateLimit `json:"rate_limit"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type updateApiKeyRecord struct {
+	ApiKey  *domain.ApiKey `json:"api_key"`
+	Version int64          `json:"version"`
+}
+
+type rotateApiKeyRecord struct {
+	ApiId          string         `json:"api_id"`
+	Successor      *domain.ApiKey `json:"successor"`
+	ExpirationDate *time.Time     `json:"expiration_date"`
+}
+
+type expireAdminTokenRecord struct {
+	TokenId        string     `json:"token_id"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+}
+
+type fileStoreSnapshot struct {
+	Seq       uint64                        `json:"seq"`
+	ApiKeys   []*domain.ApiKey              `json:"api_keys"`
+	ApiUsages map[string][]*domain.ApiUsage `json:"api_usages"`
+	// HourlyUsage and DailyUsage are missing from snapshots written before usage was rolled up
+	HourlyUsage   map[string][]*domain.UsageRollup `json:"hourly_usage"`
+	DailyUsage    map[string][]*domain.UsageRollup `json:"daily_usage"`
+	AdminTokens   []*domain.AdminToken             `json:"admin_tokens"`
+	Organizations []*domain.Organization           `json:"organizations"`
+	AuditEvents   []*domain.AuditEvent             `json:"audit_events"`
+}
+
+// NewFileStore opens (or creates) a file store in opts.Directory, recovers its state and starts the
+// background snapshot loop, which stops and closes the store when ctx is cancelled
+func NewFileStore(ctx context.Context, opts FileStoreOptions) (*FileStore, error) {
+	if err := os.MkdirAll(opts.Directory, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	fs := &FileStore{
+		mem:  NewDataStore(),
+		opts: opts,
+	}
+	if err := fs.recover(); err != nil {
+		return nil, err
+	}
+
+	if opts.SnapshotInterval > 0 {
+		go fs.snapshotLoop(ctx)
+	} else {
+		go func() {
+			<-ctx.Done()
+			fs.closeAndLog()
+		}()
+	}
+
+	return fs, nil
+}
+
+// StoreApiKey stores an API key in the data store
+func (fs *FileStore) StoreApiKey(apiKey *domain.ApiKey) error {
+	return fs.commit(opStoreApiKey, apiKey, func() error {
+		return fs.mem.StoreApiKey(apiKey)
+	})
+}
+
+// GetApiKey retrieves an API key by ID
+func (fs *FileStore) GetApiKey(apiId string) (*domain.ApiKey, bool, error) {
+	return fs.mem.GetApiKey(apiId)
+}
+
+// GetApiKeyByAddress retrieves an ecdsa API key by the address derived from it
+func (fs *FileStore) GetApiKeyByAddress(address string) (*domain.ApiKey, error) {
+	return fs.mem.GetApiKeyByAddress(address)
+}
+
+// GetApiKeyByLookupPrefix retrieves an opaque API key by the non-secret prefix of the key
+func (fs *FileStore) GetApiKeyByLookupPrefix(lookupPrefix string) (*domain.ApiKey, error) {
+	return fs.mem.GetApiKeyByLookupPrefix(lookupPrefix)
+}
+
+// GetAllApiKeys returns all API keys regardless of expiration status
+func (fs *FileStore) GetAllApiKeys() ([]*domain.ApiKey, error) {
+	return fs.mem.GetAllApiKeys()
+}
+
+// GetAllActiveApiKeys returns all API keys that haven't expired yet or nil if none exist
+func (fs *FileStore) GetAllActiveApiKeys() ([]*domain.ApiKey, error) {
+	return fs.mem.GetAllActiveApiKeys()
+}
+
+// QueryApiKeys returns one page of the keys matching a listing query
+func (fs *FileStore) QueryApiKeys(query domain.ApiKeyQuery) (*domain.ApiKeyPage, error) {
+	return fs.mem.QueryApiKeys(query)
+}
+
+// ExpireApiKey sets the expiration date of an API key to the specified time
+func (fs *FileStore) ExpireApiKey(apiId string, expirationDate *time.Time) error {
+	return fs.commit(opExpireApiKey, expireApiKeyRecord{ApiId: apiId, ExpirationDate: expirationDate}, func() error {
+		return fs.mem.ExpireApiKey(apiId, expirationDate)
+	})
+}
+
+// RevokeApiKey permanently revokes an API key and records who revoked it and why
+func (fs *FileStore) RevokeApiKey(apiId string, revocation domain.Revocation) error {
+	check := func() error {
+		return fs.mem.CheckApiKeyTransition(apiId, domain.ApiKeyStatusRevoked, revocation.RevokedAt)
+	}
+	return fs.commitChecked(opRevokeApiKey, revokeApiKeyRecord{ApiId: apiId, Revocation: revocation}, check, func() error {
+		return fs.mem.RevokeApiKey(apiId, revocation)
+	})
+}
+
+// SetApiKeyStatus suspends or resumes an API key
+func (fs *FileStore) SetApiKeyStatus(apiId string, status string, updatedAt time.Time) error {
+	check := func() error {
+		return fs.mem.CheckApiKeyTransition(apiId, status, updatedAt)
+	}
+	record := setApiKeyStatusRecord{ApiId: apiId, Status: status, UpdatedAt: updatedAt}
+	return fs.commitChecked(opSetApiKeyStatus, record, check, func() error {
+		return fs.mem.SetApiKeyStatus(apiId, status, updatedAt)
+	})
+}
+
+// PurgeApiKeys deletes API keys and their usage records. Usage records hold client IP addresses, so the
+// store snapshots right away to drop them from the write-ahead log as well.
+func (fs *FileStore) PurgeApiKeys(apiIds []string) error {
+	return fs.commit(opPurgeApiKeys, purgeApiKeysRecord{ApiIds: apiIds}, func() error {
+		if err := fs.mem.PurgeApiKeys(apiIds); err != nil {
+			return err
+		}
+		// The purge is already durable, a failed snapshot only delays removing the data from disk
+		if err := fs.snapshot(); err != nil {
+			log.Printf("Failed to snapshot file store after purge: %v", err)
+		}
+		return nil
+	})
+}
+
+// UpdateApiKey replaces an API key unless it changed since version, in which case it returns
+// domain.ErrVersionConflict
+func (fs *FileStore) UpdateApiKey(apiKey *domain.ApiKey, version int64) error {
+	// Check the version before logging, a record that fails to apply would also fail on replay
+	check := func() error {
+		return fs.mem.CheckApiKeyVersion(apiKey.ApiId, version)
+	}
+	return fs.commitChecked(opUpdateApiKey, updateApiKeyRecord{ApiKey: apiKey, Version: version}, check, func() error {
+		return fs.mem.UpdateApiKey(apiKey, version)
+	})
+}
+
+// BindApiKeyIP binds an API key to ipAddress unless it is already bound, and returns the IP the key is bound to
+func (fs *FileStore) BindApiKeyIP(apiId string, ipAddress string) (string, error) {
+	// Binding an already bound key changes nothing, so skip the log write on the hot path
+	if apiKey, exists, _ := fs.mem.GetApiKey(apiId); !exists || apiKey.BoundIP != "" {
+		return fs.mem.BindApiKeyIP(apiId, ipAddress)
+	}
+
+	var boundIP string
+	err := fs.commit(opBindApiKeyIP, bindApiKeyIPRecord{ApiId: apiId, IpAddress: ipAddress}, func() error {
+		var err error
+		boundIP, err = fs.mem.BindApiKeyIP(apiId, ipAddress)
+		return err
+	})
+	return boundIP, err
+}
+
+// ResetApiKeyIPBinding removes the IP binding of an API key
+func (fs *FileStore) ResetApiKeyIPBinding(apiId string, updatedAt time.Time) error {
+	return fs.commit(opResetApiKeyIP, resetApiKeyIPRecord{ApiId: apiId, UpdatedAt: updatedAt}, func() error {
+		return fs.mem.ResetApiKeyIPBinding(apiId, updatedAt)
+	})
+}
+
+// UpdateApiKeyNetworks replaces the allowed and denied networks of an API key
+func (fs *FileStore) UpdateApiKeyNetworks(apiId string, allowedCIDRs []string, deniedCIDRs []string, updatedAt time.Time) error {
+	record := updateNetworksRecord{ApiId: apiId, AllowedCIDRs: allowedCIDRs, DeniedCIDRs: deniedCIDRs, UpdatedAt: updatedAt}
+	return fs.commit(opUpdateNetworks, record, func() error {
+		return fs.mem.UpdateApiKeyNetworks(apiId, allowedCIDRs, deniedCIDRs, updatedAt)
+	})
+}
+
+// UpdateApiKeyRateLimit replaces the rate limit of an API key, nil falls back to the defaults
+func (fs *FileStore) UpdateApiKeyRateLimit(apiId string, rateLimit *domain.RateLimit, updatedAt time.Time) error {
+	record := updateRateLimitRecord{ApiId: apiId, RateLimit: rateLimit, UpdatedAt: updatedAt}
+	return fs.commit(opUpdateRateLimit, record, func() error {
+		return fs.mem.UpdateApiKeyRateLimit(apiId, rateLimit, updatedAt)
+	})
+}
+
+// RotateApiKey stores successor and links it to the API key it replaces, which expires at expirationDate
+func (fs *FileStore) RotateApiKey(apiId string, successor *domain.ApiKey, expirationDate *time.Time) error {
+	check := func() error {
+		return fs.mem.CheckApiKeyRotation(apiId)
+	}
+	record := rotateApiKeyRecord{ApiId: apiId, Successor: successor, ExpirationDate: expirationDate}
+	return fs.commitChecked(opRotateApiKey, record, check, func() error {
+		return fs.mem.RotateApiKey(apiId, successor, expirationDate)
+	})
+}
+
+// StoreApiUsage stores API usage data with auto-incremented CumulativeRequest
+func (fs *FileStore) StoreApiUsage(usage *domain.ApiUsage) error {
+	return fs.commit(opStoreApiUsage, usage, func() error {
+		return fs.mem.StoreApiUsage(usage)
+	})
+}
+
+// ConsumeQuota stores usage unless check, which counts the usage stored so far, refuses it. The check
+// runs under the log lock, so no other usage can be stored between it and the store.
+func (fs *FileStore) ConsumeQuota(usage *domain.ApiUsage, check func(counter domain.UsageCounter) error) error {
+	return fs.commitChecked(opStoreApiUsage, usage, func() error {
+		return check(fs.mem)
+	}, func() error {
+		return fs.mem.StoreApiUsage(usage)
+	})
+}
+
+// GetAllApiUsages returns all API usage records
+func (fs *FileStore) GetAllApiUsages() (map[string][]*domain.ApiUsage, error) {
+	return fs.mem.GetAllApiUsages()
+}
+
+// GetApiUsages returns the usage records of one API key
+func (fs *FileStore) GetApiUsages(apiId string) ([]*domain.ApiUsage, error) {
+	return fs.mem.GetApiUsages(apiId)
+}
+
+// GetApiUsageRollups returns the hourly or daily rollups of one API key, oldest first
+func (fs *FileStore) GetApiUsageRollups(apiId string, granularity string) ([]*domain.UsageRollup, error) {
+	return fs.mem.GetApiUsageRollups(apiId, granularity)
+}
+
+// PruneApiUsages deletes the usage records of validations before rawBefore and the hourly rollups of hours
+// ending by hourlyBefore
+func (fs *FileStore) PruneApiUsages(rawBefore time.Time, hourlyBefore time.Time) error {
+	record := pruneApiUsagesRecord{RawBefore: rawBefore, HourlyBefore: hourlyBefore}
+	return fs.commit(opPruneApiUsages, record, func() error {
+		return fs.mem.PruneApiUsages(rawBefore, hourlyBefore)
+	})
+}
+
+// CountApiUsages counts the accepted validations of an API key since the given time
+func (fs *FileStore) CountApiUsages(apiId string, since time.Time) (uint64, error) {
+	return fs.mem.CountApiUsages(apiId, since)
+}
+
+// CountOrganizationApiUsages counts the accepted validations of all live API keys of an organization since the given time
+func (fs *FileStore) CountOrganizationApiUsages(organizationId string, since time.Time) (uint64, error) {
+	return fs.mem.CountOrganizationApiUsages(organizationId, since)
+}
+
+// StoreAdminToken stores an admin token
+func (fs *FileStore) StoreAdminToken(adminToken *domain.AdminToken) error {
+	return fs.commit(opStoreAdminToken, adminToken, func() error {
+		return fs.mem.StoreAdminToken(adminToken)
+	})
+}
+
+// GetAdminToken retrieves an admin token by ID
+func (fs *FileStore) GetAdminToken(tokenId string) (*domain.AdminToken, bool, error) {
+	return fs.mem.GetAdminToken(tokenId)
+}
+
+// GetAdminTokenByHash retrieves an admin token by the SHA-256 hash of the token, or nil if there is none
+func (fs *FileStore) GetAdminTokenByHash(tokenHash string) (*domain.AdminToken, error) {
+	return fs.mem.GetAdminTokenByHash(tokenHash)
+}
+
+// GetAllAdminTokens returns all admin tokens regardless of expiration status
+func (fs *FileStore) GetAllAdminTokens() ([]*domain.AdminToken, error) {
+	return fs.mem.GetAllAdminTokens()
+}
+
+// ExpireAdminToken sets the expiration date of an admin token to the specified time
+func (fs *FileStore) ExpireAdminToken(tokenId string, expirationDate *time.Time) error {
+	return fs.commit(opExpireAdminToken, expireAdminTokenRecord{TokenId: tokenId, ExpirationDate: expirationDate}, func() error {
+		return fs.mem.ExpireAdminToken(tokenId, expirationDate)
+	})
+}
+
+// StoreOrganization creates or replaces an organization, renaming it on all of its keys
+func (fs *FileStore) StoreOrganization(organization *domain.Organization) error {
+	// Check the name before logging, a record that fails to apply would also fail on replay
+	check := func() error {
+		return fs.mem.CheckOrganizationName(organization)
+	}
+	return fs.commitChecked(opStoreOrganization, organization, check, func() error {
+		return fs.mem.StoreOrganization(organization)
+	})
+}
+
+// GetOrganization retrieves an organization by ID
+func (fs *FileStore) GetOrganization(organizationId string) (*domain.Organization, bool, error) {
+	return fs.mem.GetOrganization(organizationId)
+}
+
+// GetOrganizationByName retrieves an organization by name ignoring case, or nil if there is none
+func (fs *FileStore) GetOrganizationByName(name string) (*domain.Organization, error) {
+	return fs.mem.GetOrganizationByName(name)
+}
+
+// GetAllOrganizations returns all organizations regardless of status
+func (fs *FileStore) GetAllOrganizations() ([]*domain.Organization, error) {
+	return fs.mem.GetAllOrganizations()
+}
+
+// StoreAuditEvent appends an event to the audit trail
+func (fs *FileStore) StoreAuditEvent(event *domain.AuditEvent) error {
+	return fs.commit(opStoreAuditEvent, event, func() error {
+		return fs.mem.StoreAuditEvent(event)
+	})
+}
+
+// GetAllAuditEvents returns the audit trail in order of recording
+func (fs *FileStore) GetAllAuditEvents() ([]*domain.AuditEvent, error) {
+	return fs.mem.GetAllAuditEvents()
+}
+
+// Close writes a final snapshot and releases the log file. Further mutations return ErrStoreClosed.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed {
+		return nil
+	}
+
+	snapshotErr := fs.snapshot()
+	fs.closed = true
+	if err := fs.wal.Close(); err != nil {
+		return fmt.Errorf("failed to close write-ahead log: %w", err)
+	}
+	return snapshotErr
+}
+
+// commit makes a mutation durable by logging v before running apply against the in-memory state,
+// then snapshots if enough records have accumulated
+func (fs *FileStore) commit(op walOp, v any, apply func() error) error {
+	return fs.commitChecked(op, v, nil, apply)
+}
+
+// commitChecked is commit for mutations that can be refused. check runs under the log lock, so no
+// other mutation can interleave between it and apply.
+func (fs *FileStore) commitChecked(op walOp, v any, check func() error, apply func() error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if err := fs.append(op, v); err != nil {
+		return err
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+
+	if fs.opts.SnapshotEveryRecords > 0 && fs.recordsSinceSnapshot >= fs.opts.SnapshotEveryRecords {
+		// The record is already durable, so a failed snapshot only delays log truncation
+		if err := fs.snapshot(); err != nil {
+			log.Printf("Failed to snapshot file store: %v", err)
+		}
+	}
+	return nil
+}
+
+// append writes a single record to the write-ahead log and fsyncs it. Callers must hold fs.mu.
+func (fs *FileStore) append(op walOp, v any) error {
+	if fs.closed {
+		return ErrStoreClosed
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", op, err)
+	}
+
+	record := walRecord{
+		Seq:      fs.seq + 1,
+		Op:       op,
+		Data:     data,
+		Checksum: crc32.ChecksumIEEE(data),
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", op, err)
+	}
+
+	if _, err := fs.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	if err := fs.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+
+	fs.seq = record.Seq
+	fs.recordsSinceSnapshot++
+	return nil
+}
+
+// apply replays a single logged mutation against the in-memory state
+func (fs *FileStore) apply(record walRecord) error {
+	switch record.Op {
+	case opStoreApiKey:
+		var apiKey domain.ApiKey
+		if err := json.Unmarshal(record.Data, &apiKey); err != nil {
+			return err
+		}
+		return fs.mem.StoreApiKey(&apiKey)
+	case opExpireApiKey:
+		var expire expireApiKeyRecord
+		if err := json.Unmarshal(record.Data, &expire); err != nil {
+			return err
+		}
+		return fs.mem.ExpireApiKey(expire.ApiId, expire.ExpirationDate)
+	case opRevokeApiKey:
+		var revoke revokeApiKeyRecord
+		if err := json.Unmarshal(record.Data, &revoke); err != nil {
+			return err
+		}
+		return fs.mem.RevokeApiKey(revoke.ApiId, revoke.Revocation)
+	case opPurgeApiKeys:
+		var purge purgeApiKeysRecord
+		if err := json.Unmarshal(record.Data, &purge); err != nil {
+			return err
+		}
+		return fs.mem.PurgeApiKeys(purge.ApiIds)
+	case opSetApiKeyStatus:
+		var status setApiKeyStatusRecord
+		if err := json.Unmarshal(record.Data, &status); err != nil {
+			return err
+		}
+		return fs.mem.SetApiKeyStatus(status.ApiId, status.Status, status.UpdatedAt)
+	case opUpdateApiKey:
+		var update updateApiKeyRecord
+		if err := json.Unmarshal(record.Data, &update); err != nil {
+			return err
+		}
+		if update.ApiKey == nil {
+			return fmt.Errorf("%s record without API key", opUpdateApiKey)
+		}
+		return fs.mem.UpdateApiKey(update.ApiKey, update.Version)
+	case opStoreApiUsage:
+		var usage domain.ApiUsage
+		if err := json.Unmarshal(record.Data, &usage); err != nil {
+			return err
+		}
+		return fs.mem.StoreApiUsage(&usage)
+	case opPruneApiUsages:
+		var prune pruneApiUsagesRecord
+		if err := json.Unmarshal(record.Data, &prune); err != nil {
+			return err
+		}
+		return fs.mem.PruneApiUsages(prune.RawBefore, prune.HourlyBefore)
+	case opBindApiKeyIP:
+		var bind bindApiKeyIPRecord
+		if err := json.Unmarshal(record.Data, &bind); err != nil {
+			return err
+		}
+		_, err := fs.mem.BindApiKeyIP(bind.ApiId, bind.IpAddress)
+		return err
+	case opResetApiKeyIP:
+		var reset resetApiKeyIPRecord
+		if err := json.Unmarshal(record.Data, &reset); err != nil {
+			return err
+		}
+		return fs.mem.ResetApiKeyIPBinding(reset.ApiId, reset.UpdatedAt)
+	case opUpdateNetworks:
+		var update updateNetworksRecord
+		if err := json.Unmarshal(record.Data, &update); err != nil {
+			return err
+		}
+		return fs.mem.UpdateApiKeyNetworks(update.ApiId, update.AllowedCIDRs, update.DeniedCIDRs, update.UpdatedAt)
+	case opUpdateRateLimit:
+		var update updateRateLimitRecord
+		if err := json.Unmarshal(record.Data, &update); err != nil {
+			return err
+		}
+		return fs.mem.UpdateApiKeyRateLimit(update.ApiId, update.RateLimit, update.UpdatedAt)
+	case opRotateApiKey:
+		var rotate rotateApiKeyRecord
+		if err := json.Unmarshal(record.Data, &rotate); err != nil {
+			return err
+		}
+		if rotate.Successor == nil {
+			return fmt.Errorf("%s record without successor", opRotateApiKey)
+		}
+		// Logs written before rotations were checked can rotate a key twice, the first rotation wins
+		if err := fs.mem.CheckApiKeyRotation(rotate.ApiId); err != nil {
+			log.Printf("Skipping write-ahead log record %d: %v", record.Seq, err)
+			return nil
+		}
+		return fs.mem.RotateApiKey(rotate.ApiId, rotate.Successor, rotate.ExpirationDate)
+	case opStoreAdminToken:
+		var adminToken domain.AdminToken
+		if err := json.Unmarshal(record.Data, &adminToken); err != nil {
+			return err
+		}
+		return fs.mem.StoreAdminToken(&adminToken)
+	case opExpireAdminToken:
+		var expire expireAdminTokenRecord
+		if err := json.Unmarshal(record.Data, &expire); err != nil {
+			return err
+		}
+		return fs.mem.ExpireAdminToken(expire.TokenId, expire.ExpirationDate)
+	case opStoreOrganization:
+		var organization domain.Organization
+		if err := json.Unmarshal(record.Data, &organization); err != nil {
+			return err
+		}
+		return fs.mem.StoreOrganization(&organization)
+	case opStoreAuditEvent:
+		var event domain.AuditEvent
+		if err := json.Unmarshal(record.Data, &event); err != nil {
+			return err
+		}
+		return fs.mem.StoreAuditEvent(&event)
+	default:
+		return fmt.Errorf("unknown write-ahead log operation %q", record.Op)
+	}
+}
+
+// recover loads the latest snapshot, replays the write-ahead log on top of it and opens the log
+// for appending. A torn or corrupt tail left by a crash mid-write is truncated.
+func (fs *FileStore) recover() error {
+	if err := fs.loadSnapshot(); err != nil {
+		return err
+	}
+
+	walPath := filepath.Join(fs.opts.Directory, _walFileName)
+	wal, err := os.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+
+	validLength, replayed, err := fs.replay(wal)
+	if err != nil {
+		_ = wal.Close()
+		return err
+	}
+
+	if err := wal.Truncate(validLength); err != nil {
+		_ = wal.Close()
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	if _, err := wal.Seek(validLength, io.SeekStart); err != nil {
+		_ = wal.Close()
+		return fmt.Errorf("failed to seek write-ahead log: %w", err)
+	}
+
+	fs.wal = wal
+	fs.recordsSinceSnapshot = replayed
+	log.Printf("File store recovered from %s at sequence %d (%d log records replayed)", fs.opts.Directory, fs.seq, replayed)
+	return nil
+}
+
+// replay applies every intact log record newer than the snapshot and returns the length of the
+// valid prefix of the log together with the number of records applied
+func (fs *FileStore) replay(wal *os.File) (int64, int, error) {
+	reader := bufio.NewReader(wal)
+	var validLength int64
+	replayed := 0
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				log.Printf("Discarding incomplete write-ahead log record at offset %d", validLength)
+			}
+			return validLength, replayed, nil
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil || crc32.ChecksumIEEE(record.Data) != record.Checksum {
+			log.Printf("Discarding corrupt write-ahead log tail at offset %d", validLength)
+			return validLength, replayed, nil
+		}
+
+		// Records already covered by the snapshot survive a crash between snapshot and truncation
+		if record.Seq > fs.seq {
+			if err := fs.apply(record); err != nil {
+				return 0, 0, fmt.Errorf("failed to replay write-ahead log record %d: %w", record.Seq, err)
+			}
+			fs.seq = record.Seq
+			replayed++
+		}
+		validLength += int64(len(line))
+	}
+}
+
+func (fs *FileStore) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(fs.opts.Directory, _snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot fileStoreSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	fs.mem.restore(snapshot)
+	fs.seq = snapshot.Seq
+	return nil
+}
+
+// snapshot atomically replaces the snapshot file with the current state and truncates the log.
+// Callers must hold fs.mu.
+func (fs *FileStore) snapshot() error {
+	apiKeys, err := fs.mem.GetAllApiKeys()
+	if err != nil {
+		return err
+	}
+	apiUsages, err := fs.mem.GetAllApiUsages()
+	if err != nil {
+		return err
+	}
+	hourlyUsage, err := fs.mem.GetAllApiUsageRollups(domain.UsageGranularityHour)
+	if err != nil {
+		return err
+	}
+	dailyUsage, err := fs.mem.GetAllApiUsageRollups(domain.UsageGranularityDay)
+	if err != nil {
+		return err
+	}
+	adminTokens, err := fs.mem.GetAllAdminTokens()
+	if err != nil {
+		return err
+	}
+	organizations, err := fs.mem.GetAllOrganizations()
+	if err != nil {
+		return err
+	}
+	auditEvents, err := fs.mem.GetAllAuditEvents()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fileStoreSnapshot{
+		Seq:           fs.seq,
+		ApiKeys:       apiKeys,
+		ApiUsages:     apiUsages,
+		HourlyUsage:   hourlyUsage,
+		DailyUsage:    dailyUsage,
+		AdminTokens:   adminTokens,
+		Organizations: organizations,
+		AuditEvents:   auditEvents,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(fs.opts.Directory, _snapshotFileName, data); err != nil {
+		return err
+	}
+
+	// Everything in the log is now covered by the snapshot
+	if err := fs.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	if _, err := fs.wal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek write-ahead log: %w", err)
+	}
+	fs.recordsSinceSnapshot = 0
+	return nil
+}
+
+func (fs *FileStore) snapshotLoop(ctx context.Context) {
+	ticker := time.NewTicker(fs.opts.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fs.closeAndLog()
+			return
+		case <-ticker.C:
+			fs.mu.Lock()
+			if !fs.closed && fs.recordsSinceSnapshot > 0 {
+				if err := fs.snapshot(); err != nil {
+					log.Printf("Failed to snapshot file store: %v", err)
+				}
+			}
+			fs.mu.Unlock()
+		}
+	}
+}
+
+func (fs *FileStore) closeAndLog() {
+	if err := fs.Close(); err != nil {
+		log.Printf("Failed to close file store: %v", err)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file, fsyncs it and renames it over dir/name
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	// Persist the rename itself
+	dirHandle, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	defer dirHandle.Close()
+	if err := dirHandle.Sync(); err != nil {
+		return fmt.Errorf("failed to sync storage directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// LogNotifier writes notifications to the service log, for deployments without a notification channel
+type LogNotifier struct{}
+
+func NewLogNotifier() LogNotifier {
+	return LogNotifier{}
+}
+
+func (LogNotifier) Notify(_ context.Context, notification domain.Notification) error {
+	log.Printf("Notification for organization %s: %s", notification.OrganizationName, notification.Message)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a URL, which is expected to forward them to the
+// organization
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) WebhookNotifier {
+	return WebhookNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
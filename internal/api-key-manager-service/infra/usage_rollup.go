@@ -0,0 +1,163 @@
+package infra
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// rollUpUsage folds a usage record into the hourly and daily rollups of its key. Callers must hold ds.mu.
+func (ds *DataStore) rollUpUsage(usage *domain.ApiUsage) {
+	ds.hourlyUsage[usage.ApiId] = addToRollups(ds.hourlyUsage[usage.ApiId], domain.UsageGranularityHour, usage)
+	ds.dailyUsage[usage.ApiId] = addToRollups(ds.dailyUsage[usage.ApiId], domain.UsageGranularityDay, usage)
+}
+
+// addToRollups folds usage into the rollup of its period, starting the rollup with the first usage of the
+// period. Usage arrives close to time order, so the period is searched from the end.
+func addToRollups(rollups []*domain.UsageRollup, granularity string, usage *domain.ApiUsage) []*domain.UsageRollup {
+	start := domain.UsagePeriodStart(granularity, usage.ValidatedAt)
+	i := len(rollups)
+	for i > 0 && rollups[i-1].PeriodStart.After(start) {
+		i--
+	}
+	if i == 0 || !rollups[i-1].PeriodStart.Equal(start) {
+		rollup := &domain.UsageRollup{ApiId: usage.ApiId, Granularity: granularity, PeriodStart: start}
+		rollups = slices.Insert(rollups, i, rollup)
+		i++
+	}
+	rollups[i-1].Add(usage)
+	return rollups
+}
+
+// summarizeRollups rebuilds the usage summary of a key from its daily rollups
+func summarizeRollups(rollups []*domain.UsageRollup) usageSummary {
+	var summary usageSummary
+	for _, rollup := range rollups {
+		summary.TotalRequests += rollup.Requests
+		if rollup.LastUsed != nil && rollup.LastUsed.After(summary.LastUsed) {
+			summary.LastUsed = *rollup.LastUsed
+		}
+	}
+	return summary
+}
+
+// countAcceptedSince counts the accepted validations of a key from since on. Whole days and hours are
+// counted from the rollups, only the part of the hour since falls in needs the raw usage records.
+// Callers must hold ds.mu.
+func (ds *DataStore) countAcceptedSince(apiId string, since time.Time) uint64 {
+	var count uint64
+	for _, rollup := range ds.dailyUsage[apiId] {
+		if !rollup.PeriodStart.Before(since) {
+			count += rollup.Requests
+		}
+	}
+
+	dayStart := domain.UsagePeriodStart(domain.UsageGranularityDay, since)
+	if dayStart.Equal(since) {
+		return count
+	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	for _, rollup := range ds.hourlyUsage[apiId] {
+		if !rollup.PeriodStart.Before(since) && rollup.PeriodStart.Before(dayEnd) {
+			count += rollup.Requests
+		}
+	}
+
+	hourStart := domain.UsagePeriodStart(domain.UsageGranularityHour, since)
+	if hourStart.Equal(since) {
+		return count
+	}
+	hourEnd := hourStart.Add(time.Hour)
+	for _, usage := range ds.apiUsages[apiId] {
+		if usage.RejectionReason == "" && !usage.ValidatedAt.Before(since) && usage.ValidatedAt.Before(hourEnd) {
+			count++
+		}
+	}
+	return count
+}
+
+// GetApiUsageRollups returns the hourly or daily rollups of one API key, oldest first
+func (ds *DataStore) GetApiUsageRollups(apiId string, granularity string) ([]*domain.UsageRollup, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	rollups, err := ds.rollupsOf(granularity)
+	if err != nil {
+		return nil, err
+	}
+	return cloneRollups(rollups[apiId]), nil
+}
+
+// GetAllApiUsageRollups returns the hourly or daily rollups of all API keys
+func (ds *DataStore) GetAllApiUsageRollups(granularity string) (map[string][]*domain.UsageRollup, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	rollups, err := ds.rollupsOf(granularity)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]*domain.UsageRollup, len(rollups))
+	for apiId, keyRollups := range rollups {
+		result[apiId] = cloneRollups(keyRollups)
+	}
+	return result, nil
+}
+
+// PruneApiUsages deletes the usage records of validations before rawBefore and the hourly rollups of hours
+// ending by hourlyBefore. A zero time keeps everything. Daily rollups are kept as long as their key.
+func (ds *DataStore) PruneApiUsages(rawBefore time.Time, hourlyBefore time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if !rawBefore.IsZero() {
+		for apiId, usages := range ds.apiUsages {
+			// Readers only ever get copies, so the records can be deleted in place
+			usages = slices.DeleteFunc(usages, func(usage *domain.ApiUsage) bool {
+				return usage.ValidatedAt.Before(rawBefore)
+			})
+			if len(usages) == 0 {
+				delete(ds.apiUsages, apiId)
+			} else {
+				ds.apiUsages[apiId] = usages
+			}
+		}
+	}
+
+	if !hourlyBefore.IsZero() {
+		for apiId, rollups := range ds.hourlyUsage {
+			rollups = slices.DeleteFunc(rollups, func(rollup *domain.UsageRollup) bool {
+				return !rollup.PeriodEnd().After(hourlyBefore)
+			})
+			if len(rollups) == 0 {
+				delete(ds.hourlyUsage, apiId)
+			} else {
+				ds.hourlyUsage[apiId] = rollups
+			}
+		}
+	}
+	return nil
+}
+
+// rollupsOf returns the rollups of the granularity. Callers must hold ds.mu.
+func (ds *DataStore) rollupsOf(granularity string) (map[string][]*domain.UsageRollup, error) {
+	switch granularity {
+	case domain.UsageGranularityHour:
+		return ds.hourlyUsage, nil
+	case domain.UsageGranularityDay:
+		return ds.dailyUsage, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown usage granularity %q", domain.ErrInvalidRequest, granularity)
+	}
+}
+
+// cloneRollups copies rollups so they can be read while the stored ones keep being updated
+func cloneRollups(rollups []*domain.UsageRollup) []*domain.UsageRollup {
+	clones := make([]*domain.UsageRollup, len(rollups))
+	for i, rollup := range rollups {
+		clones[i] = rollup.Clone()
+	}
+	return clones
+}
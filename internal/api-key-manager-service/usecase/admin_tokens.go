@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const _rootTokenId = "root"
+
+type AdminPolicy struct {
+	// RootToken bootstraps the admin API with the admin role; a random one is generated when empty
+	RootToken string
+	// RootTokenFile is where a generated root token is written, readable by the service user only
+	RootTokenFile string
+}
+
+// AdminAuthentication resolves bearer tokens presented to the management endpoints
+type AdminAuthentication struct {
+	repo          Repository
+	rootTokenHash []byte
+}
+
+func NewAdminAuthentication(repo Repository, policy AdminPolicy) (AdminAuthentication, error) {
+	rootToken := policy.RootToken
+	if rootToken == "" {
+		var err error
+		rootToken, err = generateAdminToken()
+		if err != nil {
+			return AdminAuthentication{}, err
+		}
+		// Anyone reading the log would get the admin role, so only the location of the token is logged
+		if err := writeRootToken(policy.RootTokenFile, rootToken); err != nil {
+			return AdminAuthentication{}, fmt.Errorf("failed to write generated admin root token: %w", err)
+		}
+		log.Printf("No admin root token configured, generated one for this run in %s", policy.RootTokenFile)
+	}
+
+	rootTokenHash := sha256.Sum256([]byte(rootToken))
+	return AdminAuthentication{repo: repo, rootTokenHash: rootTokenHash[:]}, nil
+}
+
+// writeRootToken replaces the file at path with token, readable and writable by the owner only
+func writeRootToken(path string, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// A file left by an earlier run keeps its mode when truncated, so it is replaced instead
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(token + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Authenticate returns the admin token matching token, or domain.ErrUnauthenticated if there is no
+// such unexpired token
+func (a AdminAuthentication) Authenticate(_ context.Context, token string) (*domain.AdminToken, error) {
+	if token == "" {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	tokenHash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(tokenHash[:], a.rootTokenHash) == 1 {
+		return &domain.AdminToken{TokenId: _rootTokenId, Name: _rootTokenId, Roles: []string{domain.AdminRoleAdmin}}, nil
+	}
+
+	adminToken, err := a.repo.GetAdminTokenByHash(hex.EncodeToString(tokenHash[:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve admin token: %w", err)
+	}
+	if adminToken == nil {
+		return nil, domain.ErrUnauthenticated
+	}
+	if adminToken.ExpirationDate != nil && adminToken.ExpirationDate.Before(time.Now()) {
+		return nil, fmt.Errorf("%w: admin token has expired", domain.ErrUnauthenticated)
+	}
+	return adminToken, nil
+}
+
+type AdminTokens struct {
+	repo Repository
+}
+
+func NewAdminTokens(repo Repository) AdminTokens {
+	return AdminTokens{repo: repo}
+}
+
+// CreateAdminToken issues a new admin token. The token itself is only returned here, the store keeps its hash.
+func (a AdminTokens) CreateAdminToken(_ context.Context, request domain.AdminTokenRequest) (*domain.AdminTokenResponse, error) {
+	roles := lo.Uniq(request.Roles)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", domain.ErrInvalidRequest)
+	}
+	for _, role := range roles {
+		if !slices.Contains(domain.AdminRoles, role) {
+			return nil, fmt.Errorf("%w: unknown role %q", domain.ErrInvalidRequest, role)
+		}
+	}
+	if slices.Contains(roles, domain.AdminRoleOrgAdmin) != (request.OrganizationId != "") {
+		return nil, fmt.Errorf("%w: organization_id is required for, and only allowed with, the %s role", domain.ErrInvalidRequest, domain.AdminRoleOrgAdmin)
+	}
+	if request.OrganizationId != "" {
+		if _, err := getOrganization(a.repo, request.OrganizationId); err != nil {
+			if errors.Is(err, domain.ErrOrganizationNotFound) {
+				return nil, fmt.Errorf("%w: organization %s does not exist", domain.ErrInvalidRequest, request.OrganizationId)
+			}
+			return nil, err
+		}
+	}
+	if request.ExpirationDate != nil && request.ExpirationDate.Before(time.Now()) {
+		return nil, fmt.Errorf("%w: expiration_date is in the past", domain.ErrInvalidRequest)
+	}
+
+	token, err := generateAdminToken()
+	if err != nil {
+		return nil, err
+	}
+	tokenHash := sha256.Sum256([]byte(token))
+
+	adminToken := domain.AdminToken{
+		TokenId:        uuid.NewString(),
+		Name:           request.Name,
+		TokenHash:      hex.EncodeToString(tokenHash[:]),
+		Roles:          roles,
+		OrganizationId: request.OrganizationId,
+		CreatedAt:      time.Now(),
+		ExpirationDate: request.ExpirationDate,
+	}
+	if err := a.repo.StoreAdminToken(&adminToken); err != nil {
+		return nil, fmt.Errorf("failed to store admin token: %w", err)
+	}
+
+	return &domain.AdminTokenResponse{
+		TokenId:        adminToken.TokenId,
+		Token:          token,
+		Name:           adminToken.Name,
+		Roles:          adminToken.Roles,
+		OrganizationId: adminToken.OrganizationId,
+		ExpirationDate: adminToken.ExpirationDate,
+	}, nil
+}
+
+func (a AdminTokens) ListAdminTokens(_ context.Context) (*domain.AdminTokenListResponse, error) {
+	adminTokens, err := a.repo.GetAllAdminTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summaries := lo.Map(adminTokens, func(adminToken *domain.AdminToken, _ int) domain.AdminTokenSummary {
+		return domain.AdminTokenSummary{
+			TokenId:        adminToken.TokenId,
+			Name:           adminToken.Name,
+			Roles:          adminToken.Roles,
+			OrganizationId: adminToken.OrganizationId,
+			CreatedAt:      adminToken.CreatedAt,
+			ExpirationDate: adminToken.ExpirationDate,
+			IsExpired:      adminToken.ExpirationDate != nil && adminToken.ExpirationDate.Before(now),
+		}
+	})
+	slices.SortFunc(summaries, func(a, b domain.AdminTokenSummary) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return &domain.AdminTokenListResponse{
+		Tokens: summaries,
+		Total:  len(summaries),
+	}, nil
+}
+
+// RevokeAdminToken expires an admin token immediately
+func (a AdminTokens) RevokeAdminToken(_ context.Context, tokenId string) error {
+	adminToken, exists, err := a.repo.GetAdminToken(tokenId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve admin token: %w", err)
+	}
+	if !exists || adminToken == nil {
+		return fmt.Errorf("%w: %s", domain.ErrAdminTokenNotFound, tokenId)
+	}
+
+	now := time.Now()
+	if err := a.repo.ExpireAdminToken(tokenId, &now); err != nil {
+		return fmt.Errorf("failed to revoke admin token: %w", err)
+	}
+	return nil
+}
+
+func generateAdminToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate admin token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
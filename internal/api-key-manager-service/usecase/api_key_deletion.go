@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 	"time"
 )
 
@@ -14,20 +15,41 @@ func NewApiKeyDeletion(repo Repository) ApiKeyDeletion {
 	return ApiKeyDeletion{repo: repo}
 }
 
-func (a ApiKeyDeletion) ExpireApiKey(_ context.Context, apiId string) error {
+// _maxRevocationReasonLength bounds the free-text reason stored with a revoked key
+const _maxRevocationReasonLength = 500
+
+// ExpireApiKey revokes a key immediately. The admin token of the request and the optional reason are
+// recorded on the key. Keys that are already revoked or expired return domain.ErrInvalidStatusTransition.
+func (a ApiKeyDeletion) ExpireApiKey(ctx context.Context, apiId string, reason string) error {
+	return a.expireApiKey(ctx, apiId, reason, func(*domain.ApiKey) bool { return true })
+}
+
+// ExpireOrganizationApiKey expires a key of one organization. Keys of other organizations are reported as
+// not found, so their existence is not revealed.
+func (a ApiKeyDeletion) ExpireOrganizationApiKey(ctx context.Context, organizationId string, apiId string, reason string) error {
+	return a.expireApiKey(ctx, apiId, reason, func(apiKey *domain.ApiKey) bool {
+		return apiKey.OrganizationId == organizationId
+	})
+}
+
+func (a ApiKeyDeletion) expireApiKey(ctx context.Context, apiId string, reason string, include func(apiKey *domain.ApiKey) bool) error {
+	if len(reason) > _maxRevocationReasonLength {
+		return fmt.Errorf("%w: reason must be at most %d characters", domain.ErrInvalidRequest, _maxRevocationReasonLength)
+	}
+
 	// First check if the API key exists
 	apiKey, exists, err := a.repo.GetApiKey(apiId)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve API key: %w", err)
 	}
-	if !exists || apiKey == nil {
-		return fmt.Errorf("API key with ID %s not found", apiId)
+	if !exists || apiKey == nil || !include(apiKey) {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
 	}
 
-	// Set expiration date to now (immediately expired)
-	now := time.Now()
-	if err := a.repo.ExpireApiKey(apiId, &now); err != nil {
-		return fmt.Errorf("failed to expire API key: %w", err)
+	// Revoke the key now, its expiration date stays as it was
+	revocation := domain.Revocation{RevokedAt: time.Now(), RevokedBy: domain.ActorFrom(ctx), Reason: reason}
+	if err := a.repo.RevokeApiKey(apiId, revocation); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
 	}
 
 	return nil
@@ -2,65 +2,131 @@ package usecase
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"errors"
 	"fmt"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"log"
+	"net"
+	"time"
 )
 
 type ApiKeyGeneration struct {
-	repo Repository
+	repo       Repository
+	algorithms KeyAlgorithms
 }
 
-type KeyPair struct {
-	PublicKey  string
-	PrivateKey string
+func NewApiKeyGeneration(repo Repository, algorithms KeyAlgorithms) ApiKeyGeneration {
+	return ApiKeyGeneration{repo: repo, algorithms: algorithms}
 }
 
-func NewApiKeyGeneration(repo Repository) ApiKeyGeneration {
-	return ApiKeyGeneration{repo: repo}
-}
+func (a ApiKeyGeneration) GenerateApiKey(ctx context.Context, request domain.ApiKeyGeneratorRequest) (string, string, error) {
+	if err := validateKeyAlgorithm(request.KeyAlgorithm); err != nil {
+		return "", "", err
+	}
+	if err := validateEnvironment(request.Environment); err != nil {
+		return "", "", err
+	}
+	if err := validateKeyDetails(request.Name, request.Description, request.Labels); err != nil {
+		return "", "", err
+	}
+	environment := request.Environment
+	if environment == "" {
+		environment = domain.EnvironmentLive
+	}
 
-func (a ApiKeyGeneration) GenerateApiKey(_ context.Context, organizationName string) (string, string, error) {
-	apiId := uuid.NewString()
-	keyPair, err := generateKeyPair()
+	boundIP := ""
+	if request.BoundIP != "" {
+		if net.ParseIP(request.BoundIP) == nil {
+			return "", "", fmt.Errorf("%w: bound_ip %q is not a valid IP address", domain.ErrInvalidRequest, request.BoundIP)
+		}
+		boundIP = normalizeIP(request.BoundIP)
+	}
+
+	allowedCIDRs, err := normalizeCIDRs(request.AllowedCIDRs)
+	if err != nil {
+		return "", "", err
+	}
+	deniedCIDRs, err := normalizeCIDRs(request.DeniedCIDRs)
 	if err != nil {
 		return "", "", err
 	}
 
-	apiKey := domain.ApiKey{
-		ApiId:            apiId,
-		PrivateKey:       keyPair.PrivateKey,
-		OrganizationName: organizationName,
+	if err := validateRateLimit(request.RateLimit); err != nil {
+		return "", "", err
 	}
-	if err := a.repo.StoreApiKey(&apiKey); err != nil {
-		log.Printf("Failed to store api key for organization %s: %v", organizationName, err)
+	if err := validateQuota(request.Quota); err != nil {
+		return "", "", err
+	}
+	scopes, err := normalizeScopes(request.Scopes)
+	if err != nil {
 		return "", "", err
 	}
 
-	return apiId, keyPair.PublicKey, nil
-}
+	now := time.Now()
+	expiresAt := request.ExpiresAt
+	if request.TTLSeconds != nil {
+		if expiresAt != nil {
+			return "", "", fmt.Errorf("%w: only one of expires_at and ttl_seconds may be given", domain.ErrInvalidRequest)
+		}
+		if *request.TTLSeconds <= 0 {
+			return "", "", fmt.Errorf("%w: ttl_seconds must be positive", domain.ErrInvalidRequest)
+		}
+		expiresAt = lo.ToPtr(now.Add(time.Duration(*request.TTLSeconds) * time.Second))
+	}
+
+	organization, err := resolveOrganization(a.repo, request.OrganizationId, request.OrganizationName)
+	if err != nil {
+		return "", "", err
+	}
+	if organization.Status == domain.OrganizationStatusSuspended {
+		return "", "", fmt.Errorf("%w: organization %s is suspended", domain.ErrInvalidRequest, organization.OrganizationId)
+	}
 
-func generateKeyPair() (*KeyPair, error) {
-	privateKey, err := crypto.GenerateKey()
+	expirationDate, err := keyExpiration(organization, expiresAt, now)
 	if err != nil {
-		log.Printf("Failed to generate private key: %v\n", err)
-		return nil, err
+		return "", "", err
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("failed to cast public key to ECDSA")
+	// The request's algorithm takes precedence over the organization's default
+	keyAlgorithm := request.KeyAlgorithm
+	if keyAlgorithm == "" {
+		keyAlgorithm = organization.KeyAlgorithm
+	}
+	if keyAlgorithm == "" {
+		keyAlgorithm = domain.KeyAlgorithmSecp256k1
 	}
 
-	address := crypto.PubkeyToAddress(*publicKeyECDSA)
+	apiId := uuid.NewString()
+	apiKey := domain.ApiKey{
+		ApiId:            apiId,
+		Name:             request.Name,
+		Description:      request.Description,
+		Labels:           request.Labels,
+		CreatedAt:        now,
+		CreatedBy:        domain.ActorFrom(ctx),
+		UpdatedAt:        now,
+		Status:           domain.ApiKeyStatusActive,
+		KeyAlgorithm:     keyAlgorithm,
+		Environment:      environment,
+		OrganizationId:   organization.OrganizationId,
+		OrganizationName: organization.Name,
+		BoundIP:          boundIP,
+		AllowedCIDRs:     allowedCIDRs,
+		DeniedCIDRs:      deniedCIDRs,
+		RateLimit:        request.RateLimit,
+		Quota:            request.Quota,
+		Scopes:           scopes,
+		ExpirationDate:   expirationDate,
+	}
+	key, err := a.algorithms.issue(&apiKey)
+	if err != nil {
+		return "", "", err
+	}
+	if err := a.repo.StoreApiKey(&apiKey); err != nil {
+		log.Printf("Failed to store api key for organization %s: %v", organization.Name, err)
+		return "", "", err
+	}
 
-	return &KeyPair{
-		PublicKey:  fmt.Sprintf("%x", crypto.FromECDSA(privateKey)),
-		PrivateKey: address.Hex(),
-	}, nil
+	return apiId, key, nil
 }
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"time"
+)
+
+type ApiKeyIPBinding struct {
+	repo Repository
+}
+
+func NewApiKeyIPBinding(repo Repository) ApiKeyIPBinding {
+	return ApiKeyIPBinding{repo: repo}
+}
+
+// ResetIPBinding clears the IP an API key is bound to, so the next validation binds it again
+func (a ApiKeyIPBinding) ResetIPBinding(_ context.Context, apiId string) error {
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	if err := a.repo.ResetApiKeyIPBinding(apiId, time.Now()); err != nil {
+		return fmt.Errorf("failed to reset IP binding: %w", err)
+	}
+
+	return nil
+}
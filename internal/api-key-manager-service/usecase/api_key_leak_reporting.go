@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"time"
+)
+
+type ApiKeyLeakReporting struct {
+	repo       Repository
+	algorithms KeyAlgorithms
+	deletion   ApiKeyDeletion
+	notifier   Notifier
+}
+
+func NewApiKeyLeakReporting(repo Repository, algorithms KeyAlgorithms, deletion ApiKeyDeletion, notifier Notifier) ApiKeyLeakReporting {
+	return ApiKeyLeakReporting{
+		repo:       repo,
+		algorithms: algorithms,
+		deletion:   deletion,
+		notifier:   notifier,
+	}
+}
+
+// ReportLeak identifies a leaked key the same way validation does, revokes it immediately, records the
+// leak in the audit trail and notifies the owning organization. A failed notification does not fail the
+// report, the key is revoked either way.
+func (a ApiKeyLeakReporting) ReportLeak(ctx context.Context, report domain.LeakReport) (*domain.LeakReportResponse, error) {
+	source := strings.TrimSpace(report.Source)
+	if report.ApiKey == "" || source == "" {
+		return nil, fmt.Errorf("%w: api_key and source are required", domain.ErrInvalidRequest)
+	}
+
+	apiKey, err := a.algorithms.find(a.repo, report.ApiKey)
+	if errors.Is(err, domain.ErrMalformedApiKey) {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidRequest, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if apiKey == nil {
+		return nil, fmt.Errorf("%w: the reported key is not a known API key", domain.ErrApiKeyNotFound)
+	}
+
+	// Suspended keys are revoked as well, since they could otherwise be resumed
+	now := time.Now()
+	status := apiKey.StatusAt(now)
+	alreadyExpired := status == domain.ApiKeyStatusRevoked || status == domain.ApiKeyStatusExpired
+	if !alreadyExpired {
+		if err := a.deletion.ExpireApiKey(ctx, apiKey.ApiId, "leaked: "+source); err != nil {
+			return nil, err
+		}
+	}
+
+	revoked, _, err := a.repo.GetApiKey(apiKey.ApiId)
+	if err != nil || revoked == nil {
+		return nil, fmt.Errorf("failed to retrieve revoked API key: %w", err)
+	}
+
+	details := map[string]string{"source": source}
+	if report.Url != "" {
+		details["url"] = report.Url
+	}
+	if alreadyExpired {
+		details["already_expired"] = "true"
+	}
+
+	event := domain.AuditEvent{
+		EventId:        uuid.NewString(),
+		Type:           domain.AuditEventApiKeyLeaked,
+		ApiId:          apiKey.ApiId,
+		OrganizationId: apiKey.OrganizationId,
+		Details:        details,
+		OccurredAt:     now,
+	}
+	if err := a.repo.StoreAuditEvent(&event); err != nil {
+		return nil, fmt.Errorf("failed to record leak in audit trail: %w", err)
+	}
+
+	notification := domain.Notification{
+		Type:             domain.NotificationApiKeyLeaked,
+		OrganizationId:   apiKey.OrganizationId,
+		OrganizationName: apiKey.OrganizationName,
+		ApiId:            apiKey.ApiId,
+		Message:          fmt.Sprintf("API key %s was reported as leaked by %s and has been revoked", apiKey.ApiId, source),
+		Details:          details,
+		OccurredAt:       now,
+	}
+	notified := true
+	if err := a.notifier.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to notify organization %s of leaked API key %s: %v", apiKey.OrganizationName, apiKey.ApiId, err)
+		notified = false
+	}
+
+	return &domain.LeakReportResponse{
+		ApiId:          apiKey.ApiId,
+		OrganizationId: apiKey.OrganizationId,
+		Status:         revoked.StatusAt(now),
+		ExpirationDate: revoked.ExpirationDate,
+		AlreadyExpired: alreadyExpired,
+		Notified:       notified,
+	}, nil
+}
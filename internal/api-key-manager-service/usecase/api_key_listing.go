@@ -2,11 +2,22 @@ package usecase
 
 import (
 	"context"
+	"fmt"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/pkg/hyperloglog"
 	"github.com/samber/lo"
+	"slices"
+	"strings"
 	"time"
 )
 
+const (
+	_defaultPageSize = 50
+	_maxPageSize     = 200
+
+	_defaultStaleDays = 90
+)
+
 type ApiKeyListing struct {
 	repo Repository
 }
@@ -15,77 +26,232 @@ func NewApiKeyListing(repo Repository) ApiKeyListing {
 	return ApiKeyListing{repo: repo}
 }
 
-func (a ApiKeyListing) ListApiKeys(ctx context.Context) (*domain.ApiKeyListResponse, error) {
-	// Get all API keys
-	allApiKeys, err := a.repo.GetAllApiKeys()
+// ListApiKeys returns one page of the keys matching query with their usage stats
+func (a ApiKeyListing) ListApiKeys(ctx context.Context, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error) {
+	query, err := normalizeApiKeyQuery(query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get all usage data
-	allUsages, err := a.repo.GetAllApiUsages()
+	page, err := a.repo.QueryApiKeys(query)
 	if err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
-	var apiKeysWithStats []domain.ApiKeyWithStats
-
-	for _, apiKey := range allApiKeys {
-		// Calculate usage stats for this API key
-		usages := allUsages[apiKey.ApiId]
-		stats := calculateUsageStats(usages)
-
-		// Check if expired
-		isExpired := apiKey.ExpirationDate != nil && apiKey.ExpirationDate.Before(now)
-
-		apiKeyWithStats := domain.ApiKeyWithStats{
-			ApiId:            apiKey.ApiId,
-			OrganizationName: apiKey.OrganizationName,
-			ExpirationDate:   apiKey.ExpirationDate,
-			IsExpired:        isExpired,
-			UsageStats:       stats,
+	apiKeysWithStats := make([]domain.ApiKeyWithStats, 0, len(page.ApiKeys))
+	for _, apiKey := range page.ApiKeys {
+		apiKeyWithStats, err := a.withStats(apiKey, now)
+		if err != nil {
+			return nil, err
 		}
-
 		apiKeysWithStats = append(apiKeysWithStats, apiKeyWithStats)
 	}
 
-	// Sort by creation order (newest first based on API ID)
-	apiKeysWithStats = lo.Reverse(apiKeysWithStats)
-
 	return &domain.ApiKeyListResponse{
-		ApiKeys: apiKeysWithStats,
-		Total:   len(apiKeysWithStats),
+		ApiKeys:    apiKeysWithStats,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
 	}, nil
 }
 
-func calculateUsageStats(usages []*domain.ApiUsage) domain.UsageStats {
-	if len(usages) == 0 {
-		return domain.UsageStats{
-			TotalRequests: 0,
-			UniqueIPCount: 0,
+// ListOrganizationApiKeys lists only the keys of one organization, so tenants never see each other's usage
+func (a ApiKeyListing) ListOrganizationApiKeys(ctx context.Context, organizationId string, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error) {
+	query.Filter.OrganizationId = organizationId
+	return a.ListApiKeys(ctx, query)
+}
+
+// ListStaleApiKeys lists the active keys that have not been used in the last days days, least recently used
+// first. Keys that were never used count as stale once they are older than that.
+func (a ApiKeyListing) ListStaleApiKeys(ctx context.Context, days int, query domain.ApiKeyQuery) (*domain.ApiKeyListResponse, error) {
+	if days == 0 {
+		days = _defaultStaleDays
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("%w: days must be positive", domain.ErrInvalidRequest)
+	}
+
+	unusedSince := time.Now().AddDate(0, 0, -days)
+	query.Filter.UnusedSince = &unusedSince
+	if query.Filter.Status == "" {
+		query.Filter.Status = domain.ApiKeyStatusActive
+	}
+	if query.SortBy == "" {
+		query.SortBy = domain.ApiKeySortLastUsed
+		if query.Order == "" {
+			query.Order = domain.SortOrderAscending
 		}
 	}
+	return a.ListApiKeys(ctx, query)
+}
+
+// GetApiKey returns a single key with its usage stats
+func (a ApiKeyListing) GetApiKey(_ context.Context, apiId string) (*domain.ApiKeyWithStats, error) {
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	apiKeyWithStats, err := a.withStats(apiKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &apiKeyWithStats, nil
+}
+
+// GetApiKeyUsage returns the hourly or daily usage of a key, day by day unless granularity says otherwise.
+// Hourly usage only goes back as far as it is retained.
+func (a ApiKeyListing) GetApiKeyUsage(_ context.Context, apiId string, granularity string) (*domain.ApiKeyUsageResponse, error) {
+	if granularity == "" {
+		granularity = domain.UsageGranularityDay
+	}
+	if !slices.Contains(domain.UsageGranularities, granularity) {
+		return nil, fmt.Errorf("%w: granularity must be one of %s", domain.ErrInvalidRequest, strings.Join(domain.UsageGranularities, ", "))
+	}
+
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	rollups, err := a.repo.GetApiUsageRollups(apiId, granularity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API usage: %w", err)
+	}
+	return &domain.ApiKeyUsageResponse{
+		ApiId:       apiId,
+		Granularity: granularity,
+		Usage:       lo.Map(rollups, usagePeriod),
+	}, nil
+}
+
+// normalizeApiKeyQuery validates a listing query and fills in the defaults: newest keys first, one page
+// of _defaultPageSize keys
+func normalizeApiKeyQuery(query domain.ApiKeyQuery) (domain.ApiKeyQuery, error) {
+	if err := validateEnvironment(query.Filter.Environment); err != nil {
+		return query, err
+	}
+	if query.Filter.Status != "" && !slices.Contains(domain.ApiKeyStatuses, query.Filter.Status) {
+		return query, fmt.Errorf("%w: status must be one of %s", domain.ErrInvalidRequest, strings.Join(domain.ApiKeyStatuses, ", "))
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = domain.ApiKeySortCreatedAt
+	}
+	if !slices.Contains(domain.ApiKeySorts, query.SortBy) {
+		return query, fmt.Errorf("%w: sort must be one of %s", domain.ErrInvalidRequest, strings.Join(domain.ApiKeySorts, ", "))
+	}
+
+	if query.Order == "" {
+		query.Order = domain.SortOrderDescending
+	}
+	if query.Order != domain.SortOrderAscending && query.Order != domain.SortOrderDescending {
+		return query, fmt.Errorf("%w: order must be %q or %q", domain.ErrInvalidRequest, domain.SortOrderAscending, domain.SortOrderDescending)
+	}
+
+	if query.Limit == 0 {
+		query.Limit = _defaultPageSize
+	}
+	if query.Limit < 0 || query.Limit > _maxPageSize {
+		return query, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidRequest, _maxPageSize)
+	}
+	return query, nil
+}
 
-	// Find the highest cumulative request count (latest usage)
-	maxUsage := lo.MaxBy(usages, func(a, b *domain.ApiUsage) bool {
-		return a.CumulativeRequest > b.CumulativeRequest
-	})
+// withStats adds the usage stats of a key, including the consumption of its organization's quota for
+// live keys
+func (a ApiKeyListing) withStats(apiKey *domain.ApiKey, now time.Time) (domain.ApiKeyWithStats, error) {
+	rollups, err := a.repo.GetApiUsageRollups(apiKey.ApiId, domain.UsageGranularityDay)
+	if err != nil {
+		return domain.ApiKeyWithStats{}, err
+	}
 
-	// Find the most recent usage by timestamp
-	mostRecentUsage := lo.MaxBy(usages, func(a, b *domain.ApiUsage) bool {
-		return a.ValidatedAt.After(b.ValidatedAt)
-	})
+	stats := calculateUsageStats(rollups)
+	stats.Quota, err = calculateQuotaStats(apiKey.Quota, func(since time.Time) (uint64, error) {
+		return a.repo.CountApiUsages(apiKey.ApiId, since)
+	}, now)
+	if err != nil {
+		return domain.ApiKeyWithStats{}, err
+	}
+	if apiKey.OrganizationId != "" && apiKey.Environment != domain.EnvironmentTest {
+		organization, exists, err := a.repo.GetOrganization(apiKey.OrganizationId)
+		if err != nil {
+			return domain.ApiKeyWithStats{}, fmt.Errorf("failed to retrieve organization: %w", err)
+		}
+		if exists && organization.Quota != nil {
+			stats.OrganizationQuota, err = calculateQuotaStats(organization.Quota, func(since time.Time) (uint64, error) {
+				return a.repo.CountOrganizationApiUsages(organization.OrganizationId, since)
+			}, now)
+			if err != nil {
+				return domain.ApiKeyWithStats{}, err
+			}
+		}
+	}
 
-	// Count unique IP addresses
-	uniqueIPs := lo.Uniq(lo.Map(usages, func(usage *domain.ApiUsage, _ int) string {
-		return usage.IpAddress
-	}))
+	return domain.ApiKeyWithStats{
+		ApiId:            apiKey.ApiId,
+		Version:          apiKey.Version,
+		Name:             apiKey.Name,
+		Description:      apiKey.Description,
+		Labels:           apiKey.Labels,
+		CreatedAt:        apiKey.CreatedAt,
+		CreatedBy:        apiKey.CreatedBy,
+		UpdatedAt:        apiKey.UpdatedAt,
+		Status:           apiKey.StatusAt(now),
+		RevokedAt:        apiKey.RevokedAt,
+		RevokedBy:        apiKey.RevokedBy,
+		RevocationReason: apiKey.RevocationReason,
+		KeyAlgorithm:     apiKey.KeyAlgorithm,
+		Environment:      apiKey.Environment,
+		LookupPrefix:     apiKey.LookupPrefix,
+		OrganizationId:   apiKey.OrganizationId,
+		OrganizationName: apiKey.OrganizationName,
+		ExpirationDate:   apiKey.ExpirationDate,
+		IsExpired:        apiKey.ExpirationDate != nil && apiKey.ExpirationDate.Before(now),
+		BoundIP:          apiKey.BoundIP,
+		AllowedCIDRs:     apiKey.AllowedCIDRs,
+		DeniedCIDRs:      apiKey.DeniedCIDRs,
+		RateLimit:        apiKey.RateLimit,
+		Scopes:           apiKey.Scopes,
+		PredecessorId:    apiKey.PredecessorId,
+		SuccessorId:      apiKey.SuccessorId,
+		UsageStats:       stats,
+	}, nil
+}
 
-	return domain.UsageStats{
-		TotalRequests: maxUsage.CumulativeRequest,
-		LastUsed:      &mostRecentUsage.ValidatedAt,
-		UniqueIPCount: len(uniqueIPs),
-		MostRecentIP:  mostRecentUsage.IpAddress,
+// calculateUsageStats adds up the daily rollups of a key. The unique IP count is estimated from the
+// merged sketches of the days.
+func calculateUsageStats(rollups []*domain.UsageRollup) domain.UsageStats {
+	stats := domain.UsageStats{}
+	var uniqueIPs hyperloglog.Sketch
+	for _, rollup := range rollups {
+		stats.TotalRequests += rollup.Requests
+		stats.RejectedRequests += rollup.RejectedRequests
+		uniqueIPs.Merge(rollup.UniqueIPs)
+		// Rejected validations are only counted, they do not make a key "used"
+		if rollup.LastUsed != nil && (stats.LastUsed == nil || rollup.LastUsed.After(*stats.LastUsed)) {
+			stats.LastUsed = rollup.LastUsed
+			stats.MostRecentIP = rollup.MostRecentIP
+		}
 	}
-}
\ No newline at end of file
+	stats.UniqueIPCount = int(uniqueIPs.Count())
+	return stats
+}
+
+// usagePeriod presents a rollup without its sketch
+func usagePeriod(rollup *domain.UsageRollup, _ int) domain.UsagePeriod {
+	return domain.UsagePeriod{
+		PeriodStart:      rollup.PeriodStart,
+		Requests:         rollup.Requests,
+		RejectedRequests: rollup.RejectedRequests,
+		UniqueIPCount:    int(rollup.UniqueIPs.Count()),
+		LastUsed:         rollup.LastUsed,
+		MostRecentIP:     rollup.MostRecentIP,
+	}
+}
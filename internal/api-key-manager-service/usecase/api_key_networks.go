@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"time"
+)
+
+type ApiKeyNetworks struct {
+	repo Repository
+}
+
+func NewApiKeyNetworks(repo Repository) ApiKeyNetworks {
+	return ApiKeyNetworks{repo: repo}
+}
+
+// UpdateNetworks replaces the CIDR allowlist and denylist of an API key and returns the normalized lists
+func (a ApiKeyNetworks) UpdateNetworks(_ context.Context, apiId string, networks domain.ApiKeyNetworks) (*domain.ApiKeyNetworks, error) {
+	allowedCIDRs, err := normalizeCIDRs(networks.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	deniedCIDRs, err := normalizeCIDRs(networks.DeniedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	if err := a.repo.UpdateApiKeyNetworks(apiId, allowedCIDRs, deniedCIDRs, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to update API key networks: %w", err)
+	}
+
+	return &domain.ApiKeyNetworks{
+		AllowedCIDRs: allowedCIDRs,
+		DeniedCIDRs:  deniedCIDRs,
+	}, nil
+}
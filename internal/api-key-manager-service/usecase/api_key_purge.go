@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"log"
+	"strconv"
+	"time"
+)
+
+// _retentionActor is recorded as the purger of keys removed by the retention job
+const _retentionActor = "retention"
+
+type ApiKeyPurge struct {
+	repo    Repository
+	policy  RetentionPolicy
+	limiter *RateLimiter
+}
+
+type RetentionPolicy struct {
+	// PurgeRevokedAfter is how long revoked keys are kept before the retention job purges them, 0 keeps them
+	PurgeRevokedAfter time.Duration
+	// RawUsage is how long usage records are kept once rolled up, 0 keeps them
+	RawUsage time.Duration
+	// HourlyUsage is how long hourly usage rollups are kept, 0 keeps them. Daily rollups are kept as long
+	// as their key.
+	HourlyUsage time.Duration
+	// Interval is how often the retention job runs
+	Interval time.Duration
+}
+
+// IsEnabled reports whether the retention job has anything to do
+func (p RetentionPolicy) IsEnabled() bool {
+	return p.PurgeRevokedAfter > 0 || p.RawUsage > 0 || p.HourlyUsage > 0
+}
+
+func NewApiKeyPurge(repo Repository, policy RetentionPolicy, limiter *RateLimiter) ApiKeyPurge {
+	return ApiKeyPurge{repo: repo, policy: policy, limiter: limiter}
+}
+
+// PurgeApiKey permanently deletes a key of any status with all its usage records, leaving only a tombstone
+// in the audit trail
+func (a ApiKeyPurge) PurgeApiKey(ctx context.Context, apiId string) (*domain.PurgeResponse, error) {
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+	return a.purge(domain.ActorFrom(ctx), "", []*domain.ApiKey{apiKey})
+}
+
+// PurgeOrganizationApiKeys permanently deletes every key of an organization with their usage records. The
+// organization itself is kept.
+func (a ApiKeyPurge) PurgeOrganizationApiKeys(ctx context.Context, organizationId string) (*domain.PurgeResponse, error) {
+	if _, err := getOrganization(a.repo, organizationId); err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := a.repo.GetAllApiKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	apiKeys = lo.Filter(apiKeys, func(apiKey *domain.ApiKey, _ int) bool {
+		return apiKey.OrganizationId == organizationId
+	})
+	return a.purge(domain.ActorFrom(ctx), "organization", apiKeys)
+}
+
+// PurgeRevokedApiKeys purges the keys revoked longer than the retention period before now
+func (a ApiKeyPurge) PurgeRevokedApiKeys(_ context.Context, now time.Time) (*domain.PurgeResponse, error) {
+	apiKeys, err := a.repo.GetAllApiKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+
+	cutoff := now.Add(-a.policy.PurgeRevokedAfter)
+	apiKeys = lo.Filter(apiKeys, func(apiKey *domain.ApiKey, _ int) bool {
+		return apiKey.Status == domain.ApiKeyStatusRevoked && apiKey.RevokedAt != nil && apiKey.RevokedAt.Before(cutoff)
+	})
+	return a.purge(_retentionActor, "retention", apiKeys)
+}
+
+// PruneApiUsages deletes the usage records and hourly rollups that are older than retained at now
+func (a ApiKeyPurge) PruneApiUsages(_ context.Context, now time.Time) error {
+	var rawBefore, hourlyBefore time.Time
+	if a.policy.RawUsage > 0 {
+		rawBefore = now.Add(-a.policy.RawUsage)
+	}
+	if a.policy.HourlyUsage > 0 {
+		hourlyBefore = now.Add(-a.policy.HourlyUsage)
+	}
+	if rawBefore.IsZero() && hourlyBefore.IsZero() {
+		return nil
+	}
+
+	if err := a.repo.PruneApiUsages(rawBefore, hourlyBefore); err != nil {
+		return fmt.Errorf("failed to prune API usage: %w", err)
+	}
+	return nil
+}
+
+// RunRetention purges expired revocations and prunes old usage every Interval until ctx is done
+func (a ApiKeyPurge) RunRetention(ctx context.Context) {
+	ticker := time.NewTicker(a.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.runRetention(ctx, now)
+		}
+	}
+}
+
+func (a ApiKeyPurge) runRetention(ctx context.Context, now time.Time) {
+	if err := a.PruneApiUsages(ctx, now); err != nil {
+		log.Printf("Failed to prune API usage: %v", err)
+	}
+
+	if a.policy.PurgeRevokedAfter <= 0 {
+		return
+	}
+	purged, err := a.PurgeRevokedApiKeys(ctx, now)
+	if err != nil {
+		log.Printf("Failed to purge revoked API keys: %v", err)
+		return
+	}
+	if len(purged.ApiIds) > 0 {
+		log.Printf("Purged %d revoked API keys", len(purged.ApiIds))
+	}
+}
+
+// purge deletes apiKeys in one go and records a tombstone for each. The tombstones only name the key and
+// its organization, never usage details such as IP addresses.
+func (a ApiKeyPurge) purge(purgedBy string, scope string, apiKeys []*domain.ApiKey) (*domain.PurgeResponse, error) {
+	response := &domain.PurgeResponse{ApiIds: []string{}}
+	if len(apiKeys) == 0 {
+		return response, nil
+	}
+
+	// Every validation of a key is in its daily rollups, raw records may already be pruned
+	usageRecords := make(map[string]int, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		rollups, err := a.repo.GetApiUsageRollups(apiKey.ApiId, domain.UsageGranularityDay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve API usage: %w", err)
+		}
+		for _, rollup := range rollups {
+			usageRecords[apiKey.ApiId] += int(rollup.Requests + rollup.RejectedRequests)
+		}
+		response.ApiIds = append(response.ApiIds, apiKey.ApiId)
+		response.UsageRecords += usageRecords[apiKey.ApiId]
+	}
+
+	if err := a.repo.PurgeApiKeys(response.ApiIds); err != nil {
+		return nil, fmt.Errorf("failed to purge API keys: %w", err)
+	}
+	a.limiter.Forget(response.ApiIds)
+
+	now := time.Now()
+	for _, apiKey := range apiKeys {
+		details := map[string]string{"usage_records": strconv.Itoa(usageRecords[apiKey.ApiId])}
+		if purgedBy != "" {
+			details["purged_by"] = purgedBy
+		}
+		if scope != "" {
+			details["scope"] = scope
+		}
+		event := domain.AuditEvent{
+			EventId:        uuid.NewString(),
+			Type:           domain.AuditEventApiKeyPurged,
+			ApiId:          apiKey.ApiId,
+			OrganizationId: apiKey.OrganizationId,
+			Details:        details,
+			OccurredAt:     now,
+		}
+		if err := a.repo.StoreAuditEvent(&event); err != nil {
+			return nil, fmt.Errorf("failed to record purge in audit trail: %w", err)
+		}
+	}
+	return response, nil
+}
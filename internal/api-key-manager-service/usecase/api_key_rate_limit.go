@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"time"
+)
+
+type ApiKeyRateLimits struct {
+	repo Repository
+}
+
+func NewApiKeyRateLimits(repo Repository) ApiKeyRateLimits {
+	return ApiKeyRateLimits{repo: repo}
+}
+
+// UpdateRateLimit sets the rate limit of an API key. A nil limit makes the key use its organization's
+// default again, while an empty limit exempts the key from rate limiting.
+func (a ApiKeyRateLimits) UpdateRateLimit(_ context.Context, apiId string, rateLimit *domain.RateLimit) error {
+	if err := validateRateLimit(rateLimit); err != nil {
+		return err
+	}
+
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	if err := a.repo.UpdateApiKeyRateLimit(apiId, rateLimit, time.Now()); err != nil {
+		return fmt.Errorf("failed to update API key rate limit: %w", err)
+	}
+
+	return nil
+}
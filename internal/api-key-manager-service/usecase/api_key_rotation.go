@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+type ApiKeyRotation struct {
+	repo       Repository
+	policy     RotationPolicy
+	algorithms KeyAlgorithms
+}
+
+type RotationPolicy struct {
+	// DefaultGracePeriod is how long a rotated key stays valid when the request does not say
+	DefaultGracePeriod time.Duration
+}
+
+func NewApiKeyRotation(repo Repository, policy RotationPolicy, algorithms KeyAlgorithms) ApiKeyRotation {
+	return ApiKeyRotation{repo: repo, policy: policy, algorithms: algorithms}
+}
+
+// RotateApiKey issues a new key of the same algorithm that inherits the settings of apiId. The old key stays valid for
+// the grace period so clients can switch over without downtime, and both keys are linked to each other.
+func (a ApiKeyRotation) RotateApiKey(ctx context.Context, apiId string, request domain.ApiKeyRotationRequest) (*domain.ApiKeyRotationResponse, error) {
+	gracePeriod := a.policy.DefaultGracePeriod
+	if request.GracePeriodSeconds != nil {
+		if *request.GracePeriodSeconds < 0 {
+			return nil, fmt.Errorf("%w: grace_period_seconds must not be negative", domain.ErrInvalidRequest)
+		}
+		gracePeriod = time.Duration(*request.GracePeriodSeconds) * time.Second
+	}
+
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+	if apiKey.SuccessorId != "" {
+		return nil, fmt.Errorf("%w: %s was replaced by %s", domain.ErrApiKeyRotated, apiId, apiKey.SuccessorId)
+	}
+
+	now := time.Now()
+	if status := apiKey.StatusAt(now); status != domain.ApiKeyStatusActive {
+		return nil, fmt.Errorf("%w: API key %s is %s", domain.ErrInvalidRequest, apiId, status)
+	}
+
+	// The successor keeps every setting of the key it replaces but starts with a fresh lifetime, capped by
+	// its organization's maximum key lifetime
+	successor := *apiKey
+	successor.ApiId = uuid.NewString()
+	successor.Version = 0
+	successor.CreatedAt = now
+	successor.CreatedBy = domain.ActorFrom(ctx)
+	successor.UpdatedAt = now
+	successor.ExpirationDate = nil
+	organization, exists, err := a.repo.GetOrganization(apiKey.OrganizationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organization: %w", err)
+	}
+	if exists {
+		successor.ExpirationDate, err = keyExpiration(organization, nil, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	successor.PredecessorId = apiId
+	key, err := a.algorithms.issue(&successor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never extend the lifetime of a key that was already going to expire sooner
+	expirationDate := now.Add(gracePeriod)
+	if apiKey.ExpirationDate != nil && apiKey.ExpirationDate.Before(expirationDate) {
+		expirationDate = *apiKey.ExpirationDate
+	}
+
+	if err := a.repo.RotateApiKey(apiId, &successor, &expirationDate); err != nil {
+		return nil, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+
+	return &domain.ApiKeyRotationResponse{
+		ApiId:                     successor.ApiId,
+		ApiKey:                    key,
+		PredecessorId:             apiId,
+		PredecessorExpirationDate: &expirationDate,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"time"
+)
+
+type ApiKeySuspension struct {
+	repo Repository
+}
+
+func NewApiKeySuspension(repo Repository) ApiKeySuspension {
+	return ApiKeySuspension{repo: repo}
+}
+
+// SuspendApiKey makes an active key fail validation until it is resumed
+func (a ApiKeySuspension) SuspendApiKey(_ context.Context, apiId string) error {
+	return a.setStatus(apiId, domain.ApiKeyStatusSuspended)
+}
+
+// ResumeApiKey makes a suspended key usable again, unless it expired in the meantime
+func (a ApiKeySuspension) ResumeApiKey(_ context.Context, apiId string) error {
+	return a.setStatus(apiId, domain.ApiKeyStatusActive)
+}
+
+func (a ApiKeySuspension) setStatus(apiId string, status string) error {
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+
+	if err := a.repo.SetApiKeyStatus(apiId, status, time.Now()); err != nil {
+		return fmt.Errorf("failed to update API key status: %w", err)
+	}
+	return nil
+}
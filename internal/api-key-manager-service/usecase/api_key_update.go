@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+const (
+	_maxNameLength        = 100
+	_maxDescriptionLength = 1000
+	_maxLabels            = 32
+	_maxLabelLength       = 63
+)
+
+type ApiKeyUpdate struct {
+	repo Repository
+}
+
+func NewApiKeyUpdate(repo Repository) ApiKeyUpdate {
+	return ApiKeyUpdate{repo: repo}
+}
+
+// UpdateApiKey edits the name, description, expiration, scopes and labels of an API key. The request
+// must carry the version it is based on; if the key changed since, nothing is updated and
+// domain.ErrVersionConflict is returned so the caller can reload the key and retry.
+func (a ApiKeyUpdate) UpdateApiKey(_ context.Context, apiId string, request domain.ApiKeyUpdateRequest) error {
+	if request.Version <= 0 {
+		return fmt.Errorf("%w: version is required", domain.ErrInvalidRequest)
+	}
+
+	apiKey, exists, err := a.repo.GetApiKey(apiId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if !exists || apiKey == nil {
+		return fmt.Errorf("%w: %s", domain.ErrApiKeyNotFound, apiId)
+	}
+	if apiKey.Version != request.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", domain.ErrVersionConflict, apiId, apiKey.Version, request.Version)
+	}
+
+	updated := *apiKey
+	if request.Name != nil {
+		updated.Name = *request.Name
+	}
+	if request.Description != nil {
+		updated.Description = *request.Description
+	}
+	if request.Labels != nil {
+		updated.Labels = request.Labels
+	}
+	if err := validateKeyDetails(updated.Name, updated.Description, updated.Labels); err != nil {
+		return err
+	}
+
+	if request.Scopes != nil {
+		if updated.Scopes, err = normalizeScopes(*request.Scopes); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	updated.UpdatedAt = now
+	if request.ExpiresAt != nil {
+		// Moving the expiration of an expired key would bring it back to life
+		if apiKey.StatusAt(now) == domain.ApiKeyStatusExpired {
+			return fmt.Errorf("%w: API key %s has expired", domain.ErrInvalidRequest, apiId)
+		}
+		organization, exists, err := a.repo.GetOrganization(apiKey.OrganizationId)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve organization: %w", err)
+		}
+		if !exists {
+			organization = &domain.Organization{}
+		}
+		if updated.ExpirationDate, err = keyExpiration(organization, request.ExpiresAt, now); err != nil {
+			return err
+		}
+	}
+
+	if err := a.repo.UpdateApiKey(&updated, request.Version); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// validateKeyDetails checks the descriptive attributes of a key, which are free text but bounded in size
+func validateKeyDetails(name string, description string, labels map[string]string) error {
+	if utf8.RuneCountInString(name) > _maxNameLength {
+		return fmt.Errorf("%w: name must not be longer than %d characters", domain.ErrInvalidRequest, _maxNameLength)
+	}
+	if utf8.RuneCountInString(description) > _maxDescriptionLength {
+		return fmt.Errorf("%w: description must not be longer than %d characters", domain.ErrInvalidRequest, _maxDescriptionLength)
+	}
+	if len(labels) > _maxLabels {
+		return fmt.Errorf("%w: a key can have at most %d labels", domain.ErrInvalidRequest, _maxLabels)
+	}
+	for key, value := range labels {
+		if key == "" || utf8.RuneCountInString(key) > _maxLabelLength || utf8.RuneCountInString(value) > _maxLabelLength {
+			return fmt.Errorf("%w: label %q must have a key and both key and value at most %d characters", domain.ErrInvalidRequest, key, _maxLabelLength)
+		}
+	}
+	return nil
+}
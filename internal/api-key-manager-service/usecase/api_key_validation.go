@@ -2,49 +2,100 @@ package usecase
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/pkg/keyformat"
 	"github.com/ethereum/go-ethereum/crypto"
+	"strings"
 	"time"
 )
 
 type ApiKeyValidation struct {
-	repo Repository
+	repo       Repository
+	policy     ValidationPolicy
+	algorithms KeyAlgorithms
+	nonces     *nonceCache
+	limiter    *RateLimiter
 }
 
-func NewApiKeyValidation(repo Repository) ApiKeyValidation {
-	return ApiKeyValidation{repo: repo}
+type ValidationPolicy struct {
+	// AllowMultipleIPs disables binding a key to the first IP that validates it
+	AllowMultipleIPs bool
+	// AllowedTimeGap is the maximum skew between a signed request's timestamp and the server clock
+	AllowedTimeGap time.Duration
+	// NonceCacheSize bounds the number of nonces remembered for replay detection
+	NonceCacheSize int
+	// NoncesPerKey bounds the share of the nonce cache a single API key can take
+	NoncesPerKey int
+	// DefaultRateLimit applies to keys that have neither their own nor an organization rate limit
+	DefaultRateLimit *domain.RateLimit
 }
 
-func (a ApiKeyValidation) ValidateApiKey(ctx context.Context, privateKeyHex string, ipAddress string) (*domain.ApiKey, error) {
-	// Parse the private key from hex string
-	privateKeyBytes, err := hex.DecodeString(privateKeyHex)
+func NewApiKeyValidation(repo Repository, policy ValidationPolicy, algorithms KeyAlgorithms, limiter *RateLimiter) ApiKeyValidation {
+	return ApiKeyValidation{
+		repo:       repo,
+		policy:     policy,
+		algorithms: algorithms,
+		nonces:     newNonceCache(policy.NonceCacheSize, policy.NoncesPerKey),
+		limiter:    limiter,
+	}
+}
+
+// ValidateApiKey validates an API key of any algorithm sent in the clear. The key must have been granted
+// every one of requiredScopes, otherwise domain.ErrInsufficientScope is returned.
+func (a ApiKeyValidation) ValidateApiKey(ctx context.Context, key string, ipAddress string, requiredScopes []string) (*domain.ValidationResult, error) {
+	apiKey, err := a.algorithms.find(a.repo, key)
+	if errors.Is(err, domain.ErrMalformedApiKey) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key format: %w", err)
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	return a.validateKey(apiKey, ipAddress, requiredScopes)
+}
+
+// ValidateSignedRequest recovers the signer of a signed request and validates the secp256k1 API key it
+// belongs to, so the client proves possession of the key without sending it. Requests outside the allowed
+// time gap or reusing a nonce within it are rejected with domain.ErrStaleRequest and
+// domain.ErrReplayedRequest, and domain.ErrNonceLimitReached and domain.ErrNonceCacheFull ask the client
+// to retry when too many nonces of the key or of all keys are live to remember another.
+func (a ApiKeyValidation) ValidateSignedRequest(ctx context.Context, request domain.SignedRequest, ipAddress string, requiredScopes []string) (*domain.ValidationResult, error) {
+	now := time.Now()
+	signedAt := time.Unix(request.Timestamp, 0)
+	if signedAt.Before(now.Add(-a.policy.AllowedTimeGap)) || signedAt.After(now.Add(a.policy.AllowedTimeGap)) {
+		return nil, domain.ErrStaleRequest
+	}
+	if request.Nonce == "" {
+		return nil, errors.New("missing request nonce")
 	}
 
-	// Convert bytes to ECDSA private key
-	privateKey, err := crypto.ToECDSA(privateKeyBytes)
+	signature, err := hex.DecodeString(strings.TrimPrefix(request.Signature, "0x"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+		return nil, fmt.Errorf("invalid signature format: %w", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length: expected %d bytes, got %d", crypto.SignatureLength, len(signature))
 	}
 
-	// Get public key from private key
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("failed to cast public key to ECDSA")
+	// Accept both the raw recovery id (0/1) and the Ethereum style (27/28)
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
 	}
 
-	// Generate the address (which was stored as PrivateKey in ApiKey)
-	address := crypto.PubkeyToAddress(*publicKeyECDSA)
-	storedAddress := address.Hex()
+	publicKeyBytes, err := crypto.Ecrecover(signedRequestHash(request), signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	publicKey, err := crypto.UnmarshalPubkey(publicKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signer public key: %w", err)
+	}
 
-	// Find the API key by matching the stored "PrivateKey" (which is actually the address)
-	apiKey, err := a.repo.GetApiKeyByPublicKey(storedAddress)
+	// Any signature recovers to some address, so only signatures of a known key are genuine
+	address := crypto.PubkeyToAddress(*publicKey).Hex()
+	apiKey, err := a.repo.GetApiKeyByAddress(address)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
 	}
@@ -52,22 +103,241 @@ func (a ApiKeyValidation) ValidateApiKey(ctx context.Context, privateKeyHex stri
 		return nil, errors.New("invalid API key")
 	}
 
-	// Check if the key has expired
-	if apiKey.ExpirationDate != nil && apiKey.ExpirationDate.Before(time.Now()) {
-		return nil, errors.New("API key has expired")
+	// Nonces are only remembered once the signer is known to hold a usable key within its rate limit, so
+	// neither forged requests nor requests signed with a revoked, leaked key can flood the cache
+	organization, err := a.getOrganization(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitStatus, err := a.admit(apiKey, organization)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.nonces.Use(apiKey.ApiId, request.Nonce, now, signedAt.Add(a.policy.AllowedTimeGap)); err != nil {
+		return nil, err
+	}
+
+	return a.authorize(apiKey, organization, ipAddress, requiredScopes, rateLimitStatus)
+}
+
+// SignRequest produces the signature ValidateSignedRequest expects for request, using the API key
+// returned at generation time
+func SignRequest(apiKey string, request domain.SignedRequest) (string, error) {
+	privateKeyHex := apiKey
+	if key, err := keyformat.Parse(apiKey); err == nil {
+		privateKeyHex = key.Secret
+	}
+
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signature, err := crypto.Sign(signedRequestHash(request), privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign request: %w", err)
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+// signedRequestHash hashes the canonical request as an EIP-191 personal message so standard
+// Ethereum tooling (personal_sign) can produce signatures
+func signedRequestHash(request domain.SignedRequest) []byte {
+	message := request.CanonicalString()
+	return crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+}
+
+// validateKey checks a looked up API key is usable and records the usage
+func (a ApiKeyValidation) validateKey(apiKey *domain.ApiKey, ipAddress string, requiredScopes []string) (*domain.ValidationResult, error) {
+	if apiKey == nil {
+		return nil, errors.New("invalid API key")
+	}
+
+	organization, err := a.getOrganization(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitStatus, err := a.admit(apiKey, organization)
+	if err != nil {
+		return nil, err
+	}
+	return a.authorize(apiKey, organization, ipAddress, requiredScopes, rateLimitStatus)
+}
+
+// admit refuses keys that are not active and validations over the key's rate limit, without writing to the
+// store. Suspended, revoked and expired keys are refused naming their status.
+func (a ApiKeyValidation) admit(apiKey *domain.ApiKey, organization *domain.Organization) (*domain.RateLimitStatus, error) {
+	if status := apiKey.StatusAt(time.Now()); status != domain.ApiKeyStatusActive {
+		return nil, &domain.ApiKeyStatusError{Status: status}
+	}
+
+	// Throttle before any check that records a rejection, so neither accepted nor refused validations let a
+	// client hammering a key grow the usage history. Rate limited validations themselves are not recorded.
+	limit := a.effectiveRateLimit(apiKey, organization)
+	if !isRateLimited(limit) {
+		return nil, nil
+	}
+	allowed, status := a.limiter.Allow(apiKey.ApiId, *limit, time.Now())
+	if !allowed {
+		return nil, &domain.RateLimitError{Status: status}
+	}
+	return &status, nil
+}
+
+// authorize checks an admitted key may be used from ipAddress for requiredScopes and records the usage,
+// refused validations included
+func (a ApiKeyValidation) authorize(apiKey *domain.ApiKey, organization *domain.Organization, ipAddress string, requiredScopes []string, rateLimitStatus *domain.RateLimitStatus) (*domain.ValidationResult, error) {
+	ipAddress = normalizeIP(ipAddress)
+	if organization != nil && organization.Status == domain.OrganizationStatusSuspended {
+		return nil, a.recordRejection(apiKey, ipAddress, domain.ErrOrganizationSuspended)
+	}
+	if err := checkNetworks(apiKey, ipAddress); err != nil {
+		return nil, a.recordRejection(apiKey, ipAddress, err)
+	}
+	if err := checkIPBinding(apiKey, ipAddress); err != nil {
+		return nil, a.recordRejection(apiKey, ipAddress, err)
+	}
+	if err := checkScopes(apiKey, requiredScopes); err != nil {
+		return nil, a.recordRejection(apiKey, ipAddress, err)
+	}
+
+	// An unbound key is only bound once the validation passed every other check, so a refused validation
+	// never ties the key to the caller's IP
+	if err := a.bindIP(apiKey, ipAddress); err != nil {
+		if errors.Is(err, domain.ErrIPNotAllowed) {
+			return nil, a.recordRejection(apiKey, ipAddress, err)
+		}
+		return nil, err
 	}
 
-	// Store API usage
+	// Store API usage, checking the quotas in the same step so concurrent validations cannot overrun them
 	usage := &domain.ApiUsage{
 		ApiId:       apiKey.ApiId,
 		IpAddress:   ipAddress,
 		ValidatedAt: time.Now(),
 	}
 
-	if err := a.repo.StoreApiUsage(usage); err != nil {
+	var quotaExceeded bool
+	var quotaErr error
+	err := a.repo.ConsumeQuota(usage, func(counter domain.UsageCounter) error {
+		quotaExceeded, quotaErr = a.checkQuotas(apiKey, organization, counter, usage.ValidatedAt)
+		return quotaErr
+	})
+	if quotaErr != nil {
+		return nil, quotaErr
+	}
+	if err != nil {
 		// Log but don't fail validation if we can't store usage
 		fmt.Printf("Failed to store API usage: %v\n", err)
 	}
 
-	return apiKey, nil
+	return &domain.ValidationResult{
+		ApiKey:        apiKey,
+		RateLimit:     rateLimitStatus,
+		QuotaExceeded: quotaExceeded,
+	}, nil
+}
+
+// checkQuotas enforces the key's own quota and, for live keys, its organization's shared quota, counting
+// with counter. A used up hard quota rejects the validation, a used up soft quota only flags it.
+func (a ApiKeyValidation) checkQuotas(apiKey *domain.ApiKey, organization *domain.Organization, counter domain.UsageCounter, now time.Time) (bool, error) {
+	type quotaCheck struct {
+		quota domain.Quota
+		count quotaCounter
+	}
+
+	var checks []quotaCheck
+	if isQuotaEnforced(apiKey.Quota) {
+		checks = append(checks, quotaCheck{quota: *apiKey.Quota, count: func(since time.Time) (uint64, error) {
+			return counter.CountApiUsages(apiKey.ApiId, since)
+		}})
+	}
+	if organization != nil && isQuotaEnforced(organization.Quota) && apiKey.Environment != domain.EnvironmentTest {
+		checks = append(checks, quotaCheck{quota: *organization.Quota, count: func(since time.Time) (uint64, error) {
+			return counter.CountOrganizationApiUsages(organization.OrganizationId, since)
+		}})
+	}
+
+	exceeded := false
+	for _, check := range checks {
+		err := checkQuota(check.quota, check.count, now)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, domain.ErrQuotaExceeded) || quotaEnforcement(check.quota) == domain.QuotaEnforcementHard {
+			return false, err
+		}
+		exceeded = true
+	}
+	return exceeded, nil
+}
+
+// getOrganization returns the organization of a key, whose status and limits apply to the validation. Keys
+// issued before organizations existed have no organization ID and get nil.
+func (a ApiKeyValidation) getOrganization(apiKey *domain.ApiKey) (*domain.Organization, error) {
+	if apiKey.OrganizationId == "" {
+		return nil, nil
+	}
+
+	organization, exists, err := a.repo.GetOrganization(apiKey.OrganizationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organization: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	return organization, nil
+}
+
+// effectiveRateLimit returns the key's own rate limit, falling back to its organization's rate limit and
+// then the global default
+func (a ApiKeyValidation) effectiveRateLimit(apiKey *domain.ApiKey, organization *domain.Organization) *domain.RateLimit {
+	if apiKey.RateLimit != nil {
+		return apiKey.RateLimit
+	}
+	if organization != nil && organization.RateLimit != nil {
+		return organization.RateLimit
+	}
+	return a.policy.DefaultRateLimit
+}
+
+// checkIPBinding rejects keys bound to a different IP
+func checkIPBinding(apiKey *domain.ApiKey, ipAddress string) error {
+	if apiKey.BoundIP != "" && apiKey.BoundIP != ipAddress {
+		return domain.ErrIPNotAllowed
+	}
+	return nil
+}
+
+// bindIP binds an unbound key to the IP validating it, unless multiple IPs are allowed. A concurrent
+// validation may have bound the key to another IP first, which refuses this one.
+func (a ApiKeyValidation) bindIP(apiKey *domain.ApiKey, ipAddress string) error {
+	if apiKey.BoundIP != "" || a.policy.AllowMultipleIPs {
+		return nil
+	}
+
+	boundIP, err := a.repo.BindApiKeyIP(apiKey.ApiId, ipAddress)
+	if err != nil {
+		return fmt.Errorf("failed to bind API key to IP address: %w", err)
+	}
+	if boundIP != ipAddress {
+		return domain.ErrIPNotAllowed
+	}
+	return nil
+}
+
+// recordRejection stores a usage record carrying the reason a validation was refused and returns err
+func (a ApiKeyValidation) recordRejection(apiKey *domain.ApiKey, ipAddress string, err error) error {
+	usage := &domain.ApiUsage{
+		ApiId:           apiKey.ApiId,
+		IpAddress:       ipAddress,
+		ValidatedAt:     time.Now(),
+		RejectionReason: err.Error(),
+	}
+
+	if storeErr := a.repo.StoreApiUsage(usage); storeErr != nil {
+		fmt.Printf("Failed to store API usage: %v\n", storeErr)
+	}
+
+	return err
 }
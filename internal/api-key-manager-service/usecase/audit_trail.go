@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/samber/lo"
+	"slices"
+)
+
+type AuditTrail struct {
+	repo Repository
+}
+
+func NewAuditTrail(repo Repository) AuditTrail {
+	return AuditTrail{repo: repo}
+}
+
+// ListAuditEvents lists audit events newest first, only those of apiId when it is not empty
+func (a AuditTrail) ListAuditEvents(_ context.Context, apiId string) (*domain.AuditEventListResponse, error) {
+	events, err := a.repo.GetAllAuditEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	if apiId != "" {
+		events = lo.Filter(events, func(event *domain.AuditEvent, _ int) bool {
+			return event.ApiId == apiId
+		})
+	}
+	slices.SortStableFunc(events, func(a, b *domain.AuditEvent) int {
+		return b.OccurredAt.Compare(a.OccurredAt)
+	})
+
+	return &domain.AuditEventListResponse{
+		Events: events,
+		Total:  len(events),
+	}, nil
+}
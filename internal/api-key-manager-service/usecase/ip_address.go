@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/samber/lo"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// normalizeIP strips any port from ipAddress and returns the canonical form of the IP, or the trimmed
+// input if it is not an IP at all
+func normalizeIP(ipAddress string) string {
+	host := strings.TrimSpace(ipAddress)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return host
+}
+
+// normalizeCIDRs validates a list of IPv4/IPv6 networks and returns their canonical masked form.
+// Bare IP addresses are accepted as single host networks.
+func normalizeCIDRs(cidrs []string) ([]string, error) {
+	var normalized []string
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q is not a valid CIDR or IP address", domain.ErrInvalidRequest, cidr)
+			}
+			addr = addr.Unmap()
+			normalized = append(normalized, netip.PrefixFrom(addr, addr.BitLen()).String())
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a valid CIDR: %v", domain.ErrInvalidRequest, cidr, err)
+		}
+		normalized = append(normalized, prefix.Masked().String())
+	}
+	return lo.Uniq(normalized), nil
+}
+
+// matchingCIDR returns the first network in cidrs containing addr
+func matchingCIDR(addr netip.Addr, cidrs []string) (string, bool) {
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return cidr, true
+		}
+	}
+	return "", false
+}
+
+// checkNetworks rejects ipAddress if it falls in a denied network, or outside every allowed network
+// when an allowlist is configured
+func checkNetworks(apiKey *domain.ApiKey, ipAddress string) error {
+	if len(apiKey.AllowedCIDRs) == 0 && len(apiKey.DeniedCIDRs) == 0 {
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return fmt.Errorf("%w: unable to parse client IP %q", domain.ErrIPNotAllowed, ipAddress)
+	}
+	addr = addr.Unmap()
+
+	if cidr, denied := matchingCIDR(addr, apiKey.DeniedCIDRs); denied {
+		return fmt.Errorf("%w: %s is in denied network %s", domain.ErrIPNotAllowed, ipAddress, cidr)
+	}
+	if len(apiKey.AllowedCIDRs) > 0 {
+		if _, allowed := matchingCIDR(addr, apiKey.AllowedCIDRs); !allowed {
+			return fmt.Errorf("%w: %s is outside the allowed networks", domain.ErrIPNotAllowed, ipAddress)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/pkg/keyformat"
+	"github.com/ethereum/go-ethereum/crypto"
+	"slices"
+	"strings"
+)
+
+// KeyAlgorithm issues API keys of one kind and recognizes them again when they are presented
+type KeyAlgorithm interface {
+	// Name is what keys of the algorithm store in domain.ApiKey.KeyAlgorithm
+	Name() string
+	// Issue generates a new key, records on apiKey what is needed to recognize it and returns the key to
+	// hand out. The returned key itself is never stored.
+	Issue(apiKey *domain.ApiKey) (string, error)
+	// Locate returns the stored key a presented key points at when read as a key of the algorithm, or nil.
+	// The stored key may have been issued by another algorithm and still has to be verified.
+	Locate(repo Repository, key string) (*domain.ApiKey, error)
+	// Verify reports whether key is the key issued for apiKey, which was issued by the algorithm
+	Verify(apiKey *domain.ApiKey, key string) bool
+}
+
+// KeyAlgorithms dispatches to the algorithm a key was issued with
+type KeyAlgorithms struct {
+	algorithms map[string]KeyAlgorithm
+	format     KeyFormatPolicy
+}
+
+type KeyFormatPolicy struct {
+	// Prefix leads every issued key, followed by the key's environment, see keyformat.Key
+	Prefix string
+}
+
+func NewKeyAlgorithms(secrets SecretPolicy, format KeyFormatPolicy) KeyAlgorithms {
+	algorithms := []KeyAlgorithm{
+		asymmetricAlgorithm{name: domain.KeyAlgorithmSecp256k1, generate: generateSecp256k1, derive: deriveSecp256k1},
+		asymmetricAlgorithm{name: domain.KeyAlgorithmEd25519, generate: generateEd25519, derive: deriveEd25519},
+		asymmetricAlgorithm{name: domain.KeyAlgorithmP256, generate: generateP256, derive: deriveP256},
+		opaqueAlgorithm{secrets: secrets},
+	}
+
+	keyAlgorithms := KeyAlgorithms{algorithms: make(map[string]KeyAlgorithm, len(algorithms)), format: format}
+	for _, algorithm := range algorithms {
+		keyAlgorithms.algorithms[algorithm.Name()] = algorithm
+	}
+	return keyAlgorithms
+}
+
+// issue issues a new key with the algorithm named by apiKey.KeyAlgorithm, replacing any key it had. The
+// key is handed out in the checksummed format of keyformat.
+func (k KeyAlgorithms) issue(apiKey *domain.ApiKey) (string, error) {
+	algorithm, exists := k.algorithms[apiKey.KeyAlgorithm]
+	if !exists {
+		return "", fmt.Errorf("%w: unknown key algorithm %q", domain.ErrInvalidRequest, apiKey.KeyAlgorithm)
+	}
+
+	apiKey.Address, apiKey.LookupPrefix, apiKey.SecretHash = "", "", ""
+	secret, err := algorithm.Issue(apiKey)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := keyformat.New(k.format.Prefix, apiKey.Environment, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to format %s key: %w", apiKey.KeyAlgorithm, err)
+	}
+	return key.String(), nil
+}
+
+// find returns the stored key a presented key belongs to, or nil. Keys do not say which algorithm issued
+// them, so every algorithm gets to locate a stored key, but only the algorithm stored on that key decides
+// whether the presented key is really its key. Formatted keys with a bad checksum are rejected with
+// domain.ErrMalformedApiKey before the repository is consulted, and their environment must be the one
+// they were issued for; keys issued before the format existed are still accepted bare.
+func (k KeyAlgorithms) find(repo Repository, key string) (*domain.ApiKey, error) {
+	environment := ""
+	if keyformat.LooksLikeKey(key, k.format.Prefix) {
+		formatted, err := keyformat.Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", domain.ErrMalformedApiKey, err)
+		}
+		key, environment = formatted.Secret, formatted.Environment
+	}
+
+	for _, name := range domain.KeyAlgorithms {
+		apiKey, err := k.algorithms[name].Locate(repo, key)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == nil {
+			continue
+		}
+
+		algorithm, exists := k.algorithms[apiKey.KeyAlgorithm]
+		if !exists || !algorithm.Verify(apiKey, key) {
+			continue
+		}
+		if environment != "" && environment != apiKey.Environment {
+			return nil, nil
+		}
+		return apiKey, nil
+	}
+	return nil, nil
+}
+
+// validateEnvironment accepts the known environments, and the empty string for the default or any
+func validateEnvironment(environment string) error {
+	if environment != "" && !slices.Contains(domain.Environments, environment) {
+		return fmt.Errorf("%w: environment must be one of %s", domain.ErrInvalidRequest, strings.Join(domain.Environments, ", "))
+	}
+	return nil
+}
+
+// validateKeyAlgorithm accepts the known algorithms, and the empty string for the default
+func validateKeyAlgorithm(keyAlgorithm string) error {
+	if keyAlgorithm != "" && !slices.Contains(domain.KeyAlgorithms, keyAlgorithm) {
+		return fmt.Errorf("%w: key_algorithm must be one of %s", domain.ErrInvalidRequest, strings.Join(domain.KeyAlgorithms, ", "))
+	}
+	return nil
+}
+
+// asymmetricAlgorithm hands out a hex encoded private key and stores the address derived from it
+type asymmetricAlgorithm struct {
+	name     string
+	generate func() (privateKey []byte, err error)
+	derive   func(privateKey []byte) (address string, err error)
+}
+
+func (a asymmetricAlgorithm) Name() string {
+	return a.name
+}
+
+func (a asymmetricAlgorithm) Issue(apiKey *domain.ApiKey) (string, error) {
+	privateKey, err := a.generate()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s key: %w", a.name, err)
+	}
+	address, err := a.derive(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive %s address: %w", a.name, err)
+	}
+
+	apiKey.Address = address
+	return hex.EncodeToString(privateKey), nil
+}
+
+// Locate looks up the key by the address derived from it
+func (a asymmetricAlgorithm) Locate(repo Repository, key string) (*domain.ApiKey, error) {
+	address, ok := a.address(key)
+	if !ok {
+		return nil, nil
+	}
+	return repo.GetApiKeyByAddress(address)
+}
+
+func (a asymmetricAlgorithm) Verify(apiKey *domain.ApiKey, key string) bool {
+	address, ok := a.address(key)
+	return ok && address == apiKey.Address
+}
+
+// address derives the address of a hex encoded private key
+func (a asymmetricAlgorithm) address(key string) (string, bool) {
+	privateKey, err := hex.DecodeString(key)
+	if err != nil {
+		return "", false
+	}
+	address, err := a.derive(privateKey)
+	if err != nil {
+		return "", false
+	}
+	return address, true
+}
+
+func generateSecp256k1() ([]byte, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FromECDSA(privateKey), nil
+}
+
+// deriveSecp256k1 derives the Ethereum address, which is also what signed requests recover
+func deriveSecp256k1(privateKey []byte) (string, error) {
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+}
+
+// generateEd25519 hands out the seed, from which the full private key is derived
+func generateEd25519() ([]byte, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey.Seed(), nil
+}
+
+func deriveEd25519(seed []byte) (string, error) {
+	if len(seed) != ed25519.SeedSize {
+		return "", errors.New("invalid ed25519 seed length")
+	}
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	return hex.EncodeToString(publicKey), nil
+}
+
+func generateP256() ([]byte, error) {
+	privateKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey.Bytes(), nil
+}
+
+func deriveP256(privateKey []byte) (string, error) {
+	key, err := ecdh.P256().NewPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key.PublicKey().Bytes()), nil
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// nonceCache remembers recently used nonces until they fall out of the replay window. It holds at most
+// capacity entries, and at most perKey entries of any one API key so a single key cannot use up the room
+// every key shares. A nonce is never forgotten before it expires, so when every entry is still live new
+// nonces are refused with domain.ErrNonceCacheFull or domain.ErrNonceLimitReached rather than making room
+// for a replay; capacity should comfortably exceed the number of signed requests expected within one window.
+type nonceCache struct {
+	mu       sync.Mutex
+	capacity int
+	perKey   int
+	entries  map[nonceKey]time.Time // expiry keyed by nonce
+	counts   map[string]int         // live nonces per API key
+	expiries nonceHeap              // soonest expiry first
+}
+
+// nonceKey scopes a nonce to its API key, nonces only need to be unique per key
+type nonceKey struct {
+	apiId string
+	nonce string
+}
+
+type nonceEntry struct {
+	key       nonceKey
+	expiresAt time.Time
+}
+
+// nonceHeap orders nonces by expiry. Expiries follow the clients' signed timestamps, which arrive out of
+// order, so insertion order says nothing about which nonce expires first.
+type nonceHeap []nonceEntry
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(nonceEntry)) }
+
+func (h *nonceHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func newNonceCache(capacity int, perKey int) *nonceCache {
+	return &nonceCache{
+		capacity: capacity,
+		perKey:   perKey,
+		entries:  make(map[nonceKey]time.Time),
+		counts:   make(map[string]int),
+	}
+}
+
+// Use records the nonce of apiId until expiresAt. It returns domain.ErrReplayedRequest if the nonce is
+// already recorded, domain.ErrNonceLimitReached if the key has too many live nonces and
+// domain.ErrNonceCacheFull if there is no room at all until an entry expires.
+func (c *nonceCache) Use(apiId string, nonce string, now, expiresAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictExpired(now)
+	key := nonceKey{apiId: apiId, nonce: nonce}
+	if _, used := c.entries[key]; used {
+		return domain.ErrReplayedRequest
+	}
+	if c.perKey > 0 && c.counts[apiId] >= c.perKey {
+		return domain.ErrNonceLimitReached
+	}
+	if c.capacity > 0 && len(c.entries) >= c.capacity {
+		return domain.ErrNonceCacheFull
+	}
+
+	c.entries[key] = expiresAt
+	c.counts[apiId]++
+	heap.Push(&c.expiries, nonceEntry{key: key, expiresAt: expiresAt})
+	return nil
+}
+
+func (c *nonceCache) evictExpired(now time.Time) {
+	for c.expiries.Len() > 0 && !c.expiries[0].expiresAt.After(now) {
+		entry := heap.Pop(&c.expiries).(nonceEntry)
+		delete(c.entries, entry.key)
+		if c.counts[entry.key.apiId]--; c.counts[entry.key.apiId] <= 0 {
+			delete(c.counts, entry.key.apiId)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package usecase
+
+import (
+	"context"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// Notifier delivers notifications to the organization owning a key through the configured channel
+type Notifier interface {
+	Notify(ctx context.Context, notification domain.Notification) error
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"strings"
+)
+
+const (
+	// _opaqueKeySeparator splits an opaque key into its lookup prefix and secret. It never occurs in the
+	// hex encoded asymmetric keys, so the kinds can be told apart.
+	_opaqueKeySeparator = "."
+	_lookupPrefixBytes  = 6
+	_opaqueSecretBytes  = 32
+)
+
+type SecretPolicy struct {
+	// Pepper keys the HMAC that opaque key secrets are stored as. It is kept out of the repository so a
+	// dump of the stored hashes cannot be brute forced on its own.
+	Pepper []byte
+}
+
+// opaqueAlgorithm issues random tokens of which only a peppered hash is stored
+type opaqueAlgorithm struct {
+	secrets SecretPolicy
+}
+
+func (o opaqueAlgorithm) Name() string {
+	return domain.KeyAlgorithmOpaque
+}
+
+func (o opaqueAlgorithm) Issue(apiKey *domain.ApiKey) (string, error) {
+	if len(o.secrets.Pepper) == 0 {
+		return "", errors.New("opaque keys require a secret pepper")
+	}
+
+	lookupPrefix := make([]byte, _lookupPrefixBytes)
+	secret := make([]byte, _opaqueSecretBytes)
+	if _, err := rand.Read(lookupPrefix); err != nil {
+		return "", fmt.Errorf("failed to generate lookup prefix: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	apiKey.LookupPrefix = hex.EncodeToString(lookupPrefix)
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	apiKey.SecretHash = o.secrets.hashSecret(encodedSecret)
+	return apiKey.LookupPrefix + _opaqueKeySeparator + encodedSecret, nil
+}
+
+// Locate looks up an opaque key by its prefix
+func (o opaqueAlgorithm) Locate(repo Repository, key string) (*domain.ApiKey, error) {
+	lookupPrefix, _, found := strings.Cut(key, _opaqueKeySeparator)
+	if !found || len(o.secrets.Pepper) == 0 {
+		return nil, nil
+	}
+	return repo.GetApiKeyByLookupPrefix(lookupPrefix)
+}
+
+// Verify checks the secret of an opaque key against the stored hash
+func (o opaqueAlgorithm) Verify(apiKey *domain.ApiKey, key string) bool {
+	lookupPrefix, secret, found := strings.Cut(key, _opaqueKeySeparator)
+	if !found || len(o.secrets.Pepper) == 0 || lookupPrefix != apiKey.LookupPrefix {
+		return false
+	}
+	return hmac.Equal([]byte(o.secrets.hashSecret(secret)), []byte(apiKey.SecretHash))
+}
+
+func (s SecretPolicy) hashSecret(secret string) string {
+	mac := hmac.New(sha256.New, s.Pepper)
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
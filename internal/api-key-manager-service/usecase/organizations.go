@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/google/uuid"
+)
+
+type Organizations struct {
+	repo Repository
+}
+
+func NewOrganizations(repo Repository) Organizations {
+	return Organizations{repo: repo}
+}
+
+func (o Organizations) CreateOrganization(_ context.Context, request domain.OrganizationRequest) (*domain.Organization, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", domain.ErrInvalidRequest)
+	}
+	if err := validateKeyAlgorithm(request.KeyAlgorithm); err != nil {
+		return nil, err
+	}
+	if err := validateMaxKeyLifetime(request.MaxKeyLifetimeSeconds); err != nil {
+		return nil, err
+	}
+
+	organization := domain.Organization{
+		Name:         name,
+		Metadata:     request.Metadata,
+		KeyAlgorithm: request.KeyAlgorithm,
+	}
+	if request.MaxKeyLifetimeSeconds != nil {
+		organization.MaxKeyLifetimeSeconds = *request.MaxKeyLifetimeSeconds
+	}
+	return createOrganization(o.repo, organization)
+}
+
+func (o Organizations) ListOrganizations(_ context.Context) (*domain.OrganizationListResponse, error) {
+	organizations, err := o.repo.GetAllOrganizations()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(organizations, func(a, b *domain.Organization) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return &domain.OrganizationListResponse{
+		Organizations: organizations,
+		Total:         len(organizations),
+	}, nil
+}
+
+func (o Organizations) GetOrganization(_ context.Context, organizationId string) (*domain.Organization, error) {
+	return getOrganization(o.repo, organizationId)
+}
+
+// UpdateOrganization renames an organization, replaces its metadata, changes its default key algorithm
+// and/or its maximum key lifetime. Renaming also renames the organization on all of its keys. A new maximum
+// lifetime only applies to keys issued afterwards.
+func (o Organizations) UpdateOrganization(_ context.Context, organizationId string, request domain.OrganizationRequest) (*domain.Organization, error) {
+	if err := validateKeyAlgorithm(request.KeyAlgorithm); err != nil {
+		return nil, err
+	}
+	if err := validateMaxKeyLifetime(request.MaxKeyLifetimeSeconds); err != nil {
+		return nil, err
+	}
+
+	organization, err := getOrganization(o.repo, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *organization
+	if name := strings.TrimSpace(request.Name); name != "" {
+		updated.Name = name
+	}
+	if request.Metadata != nil {
+		updated.Metadata = request.Metadata
+	}
+	if request.KeyAlgorithm != "" {
+		updated.KeyAlgorithm = request.KeyAlgorithm
+	}
+	if request.MaxKeyLifetimeSeconds != nil {
+		updated.MaxKeyLifetimeSeconds = *request.MaxKeyLifetimeSeconds
+	}
+	updated.UpdatedAt = time.Now()
+
+	if err := o.repo.StoreOrganization(&updated); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return &updated, nil
+}
+
+// UpdateOrganizationLimits replaces the rate limit and quota of an organization. Its keys use the new limits
+// from their next validation on.
+func (o Organizations) UpdateOrganizationLimits(_ context.Context, organizationId string, limits domain.OrganizationLimits) (*domain.Organization, error) {
+	if err := validateRateLimit(limits.RateLimit); err != nil {
+		return nil, err
+	}
+	if err := validateQuota(limits.Quota); err != nil {
+		return nil, err
+	}
+
+	organization, err := getOrganization(o.repo, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *organization
+	updated.RateLimit = limits.RateLimit
+	updated.Quota = limits.Quota
+	updated.UpdatedAt = time.Now()
+
+	if err := o.repo.StoreOrganization(&updated); err != nil {
+		return nil, fmt.Errorf("failed to update organization limits: %w", err)
+	}
+	return &updated, nil
+}
+
+// SuspendOrganization makes every key of the organization fail validation until it is resumed
+func (o Organizations) SuspendOrganization(_ context.Context, organizationId string) (*domain.Organization, error) {
+	return o.setStatus(organizationId, domain.OrganizationStatusSuspended)
+}
+
+func (o Organizations) ResumeOrganization(_ context.Context, organizationId string) (*domain.Organization, error) {
+	return o.setStatus(organizationId, domain.OrganizationStatusActive)
+}
+
+func (o Organizations) setStatus(organizationId string, status string) (*domain.Organization, error) {
+	organization, err := getOrganization(o.repo, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *organization
+	updated.Status = status
+	updated.UpdatedAt = time.Now()
+
+	if err := o.repo.StoreOrganization(&updated); err != nil {
+		return nil, fmt.Errorf("failed to update organization status: %w", err)
+	}
+	return &updated, nil
+}
+
+func getOrganization(repo Repository, organizationId string) (*domain.Organization, error) {
+	organization, exists, err := repo.GetOrganization(organizationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organization: %w", err)
+	}
+	if !exists || organization == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrOrganizationNotFound, organizationId)
+	}
+	return organization, nil
+}
+
+// createOrganization stores a new active organization with the name and settings of organization
+func createOrganization(repo Repository, organization domain.Organization) (*domain.Organization, error) {
+	now := time.Now()
+	organization.OrganizationId = uuid.NewString()
+	organization.Status = domain.OrganizationStatusActive
+	organization.CreatedAt = now
+	organization.UpdatedAt = now
+	if err := repo.StoreOrganization(&organization); err != nil {
+		return nil, fmt.Errorf("failed to store organization: %w", err)
+	}
+	return &organization, nil
+}
+
+// resolveOrganization returns the organization a new key is issued for. Requests naming an organization
+// rather than referencing its ID use the organization of that name, which is created if it does not exist.
+func resolveOrganization(repo Repository, organizationId string, organizationName string) (*domain.Organization, error) {
+	if organizationId != "" {
+		organization, err := getOrganization(repo, organizationId)
+		if errors.Is(err, domain.ErrOrganizationNotFound) {
+			return nil, fmt.Errorf("%w: organization %s does not exist", domain.ErrInvalidRequest, organizationId)
+		}
+		return organization, err
+	}
+
+	name := strings.TrimSpace(organizationName)
+	if name == "" {
+		return nil, fmt.Errorf("%w: organization_id or organization_name is required", domain.ErrInvalidRequest)
+	}
+
+	organization, err := repo.GetOrganizationByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve organization: %w", err)
+	}
+	if organization != nil {
+		return organization, nil
+	}
+
+	organization, err = createOrganization(repo, domain.Organization{Name: name})
+	if errors.Is(err, domain.ErrOrganizationExists) {
+		// Created concurrently by another request
+		return repo.GetOrganizationByName(name)
+	}
+	return organization, err
+}
+
+func validateMaxKeyLifetime(maxKeyLifetimeSeconds *int64) error {
+	if maxKeyLifetimeSeconds != nil && *maxKeyLifetimeSeconds < 0 {
+		return fmt.Errorf("%w: max_key_lifetime_seconds must not be negative", domain.ErrInvalidRequest)
+	}
+	return nil
+}
+
+// keyExpiration returns when a key issued now for organization expires. An explicit expiration must lie in
+// the future and within the organization's maximum key lifetime; without one, keys of organizations with a
+// maximum lifetime expire when it runs out and others never expire.
+func keyExpiration(organization *domain.Organization, expiresAt *time.Time, now time.Time) (*time.Time, error) {
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at is not in the future", domain.ErrInvalidRequest)
+	}
+	if organization.MaxKeyLifetimeSeconds == 0 {
+		return expiresAt, nil
+	}
+
+	latest := now.Add(time.Duration(organization.MaxKeyLifetimeSeconds) * time.Second)
+	if expiresAt == nil {
+		return &latest, nil
+	}
+	if expiresAt.After(latest) {
+		return nil, fmt.Errorf("%w: keys of organization %s may live at most %d seconds", domain.ErrInvalidRequest, organization.Name, organization.MaxKeyLifetimeSeconds)
+	}
+	return expiresAt, nil
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+const (
+	_quotaWindowDaily   = "daily"
+	_quotaWindowMonthly = "monthly"
+)
+
+// quotaCounter counts accepted validations since a point in time
+type quotaCounter func(since time.Time) (uint64, error)
+
+// dayWindow returns the start of the current UTC day and the start of the next one
+func dayWindow(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// monthWindow returns the start of the current UTC calendar month and the start of the next one
+func monthWindow(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func isQuotaEnforced(quota *domain.Quota) bool {
+	return quota != nil && (quota.Daily > 0 || quota.Monthly > 0)
+}
+
+func validateQuota(quota *domain.Quota) error {
+	if quota == nil {
+		return nil
+	}
+	switch quota.Enforcement {
+	case "", domain.QuotaEnforcementHard, domain.QuotaEnforcementSoft:
+		return nil
+	default:
+		return fmt.Errorf("%w: quota enforcement must be %q or %q", domain.ErrInvalidRequest, domain.QuotaEnforcementHard, domain.QuotaEnforcementSoft)
+	}
+}
+
+// checkQuota reports whether the accepted validations counted by count have used up quota, returning a
+// *domain.QuotaError describing the first exhausted window
+func checkQuota(quota domain.Quota, count quotaCounter, now time.Time) error {
+	if quota.Daily > 0 {
+		dayStart, dayEnd := dayWindow(now)
+		used, err := count(dayStart)
+		if err != nil {
+			return fmt.Errorf("failed to count daily usage: %w", err)
+		}
+		if used >= quota.Daily {
+			return &domain.QuotaError{Window: _quotaWindowDaily, ResetsAt: dayEnd}
+		}
+	}
+
+	if quota.Monthly > 0 {
+		monthStart, monthEnd := monthWindow(now)
+		used, err := count(monthStart)
+		if err != nil {
+			return fmt.Errorf("failed to count monthly usage: %w", err)
+		}
+		if used >= quota.Monthly {
+			return &domain.QuotaError{Window: _quotaWindowMonthly, ResetsAt: monthEnd}
+		}
+	}
+
+	return nil
+}
+
+// calculateQuotaStats reports the consumed and remaining quota for the validations counted by count in the
+// current windows
+func calculateQuotaStats(quota *domain.Quota, count quotaCounter, now time.Time) (*domain.QuotaStats, error) {
+	if !isQuotaEnforced(quota) {
+		return nil, nil
+	}
+
+	stats := &domain.QuotaStats{Enforcement: quotaEnforcement(*quota)}
+	if quota.Daily > 0 {
+		dayStart, dayEnd := dayWindow(now)
+		used, err := count(dayStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count daily usage: %w", err)
+		}
+		stats.Daily = quotaUsage(quota.Daily, used, dayEnd)
+	}
+	if quota.Monthly > 0 {
+		monthStart, monthEnd := monthWindow(now)
+		used, err := count(monthStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count monthly usage: %w", err)
+		}
+		stats.Monthly = quotaUsage(quota.Monthly, used, monthEnd)
+	}
+	return stats, nil
+}
+
+func quotaUsage(limit, used uint64, resetsAt time.Time) *domain.QuotaUsage {
+	remaining := uint64(0)
+	if used < limit {
+		remaining = limit - used
+	}
+	return &domain.QuotaUsage{
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+		ResetsAt:  resetsAt,
+	}
+}
+
+func quotaEnforcement(quota domain.Quota) string {
+	if quota.Enforcement == "" {
+		return domain.QuotaEnforcementHard
+	}
+	return quota.Enforcement
+}
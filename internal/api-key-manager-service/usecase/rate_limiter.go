@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+)
+
+// _bucketSweepInterval is how often buckets of keys that stopped being used are dropped
+const _bucketSweepInterval = time.Minute
+
+// RateLimiter keeps a per-second and a per-minute token bucket for every rate limited key. Buckets live
+// in memory only, so limits are enforced per service instance. Buckets that have refilled are no
+// different from new ones and are dropped, so only recently used keys take up memory.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*keyBuckets // keyed by ApiId
+	sweptAt time.Time
+}
+
+type keyBuckets struct {
+	limit     domain.RateLimit
+	perSecond *tokenBucket
+	perMinute *tokenBucket
+}
+
+type tokenBucket struct {
+	capacity   float64
+	refillRate float64 // tokens per second
+	tokens     float64
+	updatedAt  time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[string]*keyBuckets)}
+}
+
+// Allow takes one token from every bucket of apiId if all of them have one, and reports the state of the
+// most constrained bucket
+func (l *RateLimiter) Allow(apiId string, limit domain.RateLimit, now time.Time) (bool, domain.RateLimitStatus) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.sweptAt) >= _bucketSweepInterval {
+		l.sweep(now)
+	}
+
+	buckets, exists := l.buckets[apiId]
+	if !exists || buckets.limit != limit {
+		// New key or changed limit: start from full buckets
+		buckets = newKeyBuckets(limit, now)
+		l.buckets[apiId] = buckets
+	}
+
+	active := buckets.active()
+	allowed := true
+	for _, bucket := range active {
+		bucket.refill(now)
+		if bucket.tokens < 1 {
+			allowed = false
+		}
+	}
+	if allowed {
+		for _, bucket := range active {
+			bucket.tokens--
+		}
+	}
+
+	var status domain.RateLimitStatus
+	for i, bucket := range active {
+		remaining := int(math.Floor(bucket.tokens))
+		if i == 0 || remaining < status.Remaining {
+			status.Limit = int(bucket.capacity)
+			status.Remaining = remaining
+			status.ResetAfter = bucket.durationUntil(bucket.capacity)
+		}
+		if !allowed && bucket.tokens < 1 {
+			status.RetryAfter = max(status.RetryAfter, bucket.durationUntil(1))
+		}
+	}
+
+	return allowed, status
+}
+
+// Forget drops the buckets of keys that are gone for good
+func (l *RateLimiter) Forget(apiIds []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, apiId := range apiIds {
+		delete(l.buckets, apiId)
+	}
+}
+
+// sweep drops the buckets that have refilled to capacity. Callers must hold l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	for apiId, buckets := range l.buckets {
+		if buckets.isFull(now) {
+			delete(l.buckets, apiId)
+		}
+	}
+	l.sweptAt = now
+}
+
+func newKeyBuckets(limit domain.RateLimit, now time.Time) *keyBuckets {
+	buckets := &keyBuckets{limit: limit}
+	if limit.RequestsPerSecond > 0 {
+		capacity := float64(limit.Burst)
+		if capacity <= 0 {
+			capacity = math.Max(1, math.Ceil(limit.RequestsPerSecond))
+		}
+		buckets.perSecond = &tokenBucket{capacity: capacity, refillRate: limit.RequestsPerSecond, tokens: capacity, updatedAt: now}
+	}
+	if limit.RequestsPerMinute > 0 {
+		capacity := float64(limit.RequestsPerMinute)
+		buckets.perMinute = &tokenBucket{capacity: capacity, refillRate: capacity / 60, tokens: capacity, updatedAt: now}
+	}
+	return buckets
+}
+
+func (k *keyBuckets) active() []*tokenBucket {
+	var active []*tokenBucket
+	for _, bucket := range []*tokenBucket{k.perSecond, k.perMinute} {
+		if bucket != nil {
+			active = append(active, bucket)
+		}
+	}
+	return active
+}
+
+// isFull reports whether every bucket has refilled to capacity by now
+func (k *keyBuckets) isFull(now time.Time) bool {
+	for _, bucket := range k.active() {
+		bucket.refill(now)
+		if bucket.tokens < bucket.capacity {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.refillRate)
+		b.updatedAt = now
+	}
+}
+
+// durationUntil returns how long until the bucket holds the given number of tokens
+func (b *tokenBucket) durationUntil(tokens float64) time.Duration {
+	if b.tokens >= tokens {
+		return 0
+	}
+	return time.Duration((tokens - b.tokens) / b.refillRate * float64(time.Second))
+}
+
+// isRateLimited reports whether limit enforces anything
+func isRateLimited(limit *domain.RateLimit) bool {
+	return limit != nil && (limit.RequestsPerSecond > 0 || limit.RequestsPerMinute > 0)
+}
+
+func validateRateLimit(limit *domain.RateLimit) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.RequestsPerSecond < 0 || limit.RequestsPerMinute < 0 || limit.Burst < 0 {
+		return fmt.Errorf("%w: rate limit values must not be negative", domain.ErrInvalidRequest)
+	}
+	if limit.Burst > 0 && limit.RequestsPerSecond == 0 {
+		return fmt.Errorf("%w: burst requires requests_per_second", domain.ErrInvalidRequest)
+	}
+	return nil
+}
@@ -8,10 +8,38 @@ import (
 type Repository interface {
 	StoreApiKey(apiKey *domain.ApiKey) error
 	GetApiKey(apiId string) (*domain.ApiKey, bool, error)
-	GetApiKeyByPublicKey(privateKey string) (*domain.ApiKey, error)
+	GetApiKeyByAddress(address string) (*domain.ApiKey, error)
+	GetApiKeyByLookupPrefix(lookupPrefix string) (*domain.ApiKey, error)
 	GetAllApiKeys() ([]*domain.ApiKey, error)
 	GetAllActiveApiKeys() ([]*domain.ApiKey, error)
+	QueryApiKeys(query domain.ApiKeyQuery) (*domain.ApiKeyPage, error)
 	ExpireApiKey(apiId string, expirationDate *time.Time) error
+	RevokeApiKey(apiId string, revocation domain.Revocation) error
+	SetApiKeyStatus(apiId string, status string, updatedAt time.Time) error
+	PurgeApiKeys(apiIds []string) error
+	UpdateApiKey(apiKey *domain.ApiKey, version int64) error
+	BindApiKeyIP(apiId string, ipAddress string) (string, error)
+	ResetApiKeyIPBinding(apiId string, updatedAt time.Time) error
+	UpdateApiKeyNetworks(apiId string, allowedCIDRs []string, deniedCIDRs []string, updatedAt time.Time) error
+	UpdateApiKeyRateLimit(apiId string, rateLimit *domain.RateLimit, updatedAt time.Time) error
+	RotateApiKey(apiId string, successor *domain.ApiKey, expirationDate *time.Time) error
 	StoreApiUsage(usage *domain.ApiUsage) error
+	ConsumeQuota(usage *domain.ApiUsage, check func(counter domain.UsageCounter) error) error
 	GetAllApiUsages() (map[string][]*domain.ApiUsage, error)
+	GetApiUsages(apiId string) ([]*domain.ApiUsage, error)
+	GetApiUsageRollups(apiId string, granularity string) ([]*domain.UsageRollup, error)
+	PruneApiUsages(rawBefore time.Time, hourlyBefore time.Time) error
+	CountApiUsages(apiId string, since time.Time) (uint64, error)
+	CountOrganizationApiUsages(organizationId string, since time.Time) (uint64, error)
+	StoreAdminToken(adminToken *domain.AdminToken) error
+	GetAdminToken(tokenId string) (*domain.AdminToken, bool, error)
+	GetAdminTokenByHash(tokenHash string) (*domain.AdminToken, error)
+	GetAllAdminTokens() ([]*domain.AdminToken, error)
+	ExpireAdminToken(tokenId string, expirationDate *time.Time) error
+	StoreOrganization(organization *domain.Organization) error
+	GetOrganization(organizationId string) (*domain.Organization, bool, error)
+	GetOrganizationByName(name string) (*domain.Organization, error)
+	GetAllOrganizations() ([]*domain.Organization, error)
+	StoreAuditEvent(event *domain.AuditEvent) error
+	GetAllAuditEvents() ([]*domain.AuditEvent, error)
 }
@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/samber/lo"
+)
+
+// normalizeScopes validates the scopes a key is granted, e.g. "orders:read", and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || strings.ContainsFunc(scope, unicode.IsSpace) {
+			return nil, fmt.Errorf("%w: %q is not a valid scope", domain.ErrInvalidRequest, scope)
+		}
+		normalized = append(normalized, scope)
+	}
+	return lo.Uniq(normalized), nil
+}
+
+// checkScopes rejects keys that were not granted every required scope
+func checkScopes(apiKey *domain.ApiKey, requiredScopes []string) error {
+	missing, _ := lo.Difference(requiredScopes, apiKey.Scopes)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrInsufficientScope, strings.Join(missing, ", "))
+	}
+	return nil
+}
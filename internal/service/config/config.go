@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// _configPathEnv overrides the location of the YAML configuration file
+	_configPathEnv     = "API_KEY_MANAGER_CONFIG"
+	_defaultConfigPath = "config/default.yaml"
+	// _adminRootTokenEnv overrides AUTH.ROOT_TOKEN so the secret need not live in the configuration file
+	_adminRootTokenEnv = "ADMIN_ROOT_TOKEN"
+	// _secretPepperEnv overrides SECRETS.PEPPER
+	_secretPepperEnv = "API_KEY_PEPPER"
+
+	StorageDriverMemory = "memory"
+	StorageDriverFile   = "file"
+
+	NotificationChannelLog     = "log"
+	NotificationChannelWebhook = "webhook"
+)
+
+type Config struct {
+	AllowMultipleIPs           bool          `yaml:"ALLOW_MULTIPLE_IPS"`
+	AllowedTimeGapSeconds      int           `yaml:"ALLOWED_TIME_GAP_SECONDS"`
+	NonceCacheSize             int           `yaml:"NONCE_CACHE_SIZE"`
+	NoncesPerKey               int           `yaml:"NONCES_PER_KEY"`
+	TrustedProxies             []string      `yaml:"TRUSTED_PROXIES"`
+	TrustedProxyHeader         string        `yaml:"TRUSTED_PROXY_HEADER"` // Forwarded, X-Forwarded-For (default) or X-Real-IP
+	RateLimits                 RateLimits    `yaml:"RATE_LIMITS"`
+	RotationGracePeriodSeconds int           `yaml:"ROTATION_GRACE_PERIOD_SECONDS"`
+	Auth                       AuthConfig    `yaml:"AUTH"`
+	Secrets                    SecretsConfig `yaml:"SECRETS"`
+	KeyFormat                  KeyFormat     `yaml:"KEY_FORMAT"`
+	Notifications              Notifications `yaml:"NOTIFICATIONS"`
+	Storage                    StorageConfig `yaml:"STORAGE"`
+	Retention                  Retention     `yaml:"RETENTION"`
+}
+
+type RateLimits struct {
+	Default *RateLimit `yaml:"DEFAULT"`
+}
+
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"REQUESTS_PER_SECOND"`
+	RequestsPerMinute int     `yaml:"REQUESTS_PER_MINUTE"`
+	Burst             int     `yaml:"BURST"`
+}
+
+type AuthConfig struct {
+	RootToken           string `yaml:"ROOT_TOKEN"`
+	RequireGatewayToken bool   `yaml:"REQUIRE_GATEWAY_TOKEN"`
+}
+
+type SecretsConfig struct {
+	Pepper string `yaml:"PEPPER"`
+}
+
+type KeyFormat struct {
+	Prefix string `yaml:"PREFIX"`
+}
+
+type Notifications struct {
+	Channel        string `yaml:"CHANNEL"` // log (default) or webhook
+	WebhookUrl     string `yaml:"WEBHOOK_URL"`
+	TimeoutSeconds int    `yaml:"TIMEOUT_SECONDS"`
+}
+
+type StorageConfig struct {
+	Driver                  string `yaml:"DRIVER"`
+	Directory               string `yaml:"DIRECTORY"`
+	SnapshotIntervalSeconds int    `yaml:"SNAPSHOT_INTERVAL_SECONDS"`
+	SnapshotEveryRecords    int    `yaml:"SNAPSHOT_EVERY_RECORDS"`
+}
+
+type Retention struct {
+	PurgeRevokedAfterDays int `yaml:"PURGE_REVOKED_AFTER_DAYS"` // 0 keeps revoked keys forever
+	RawUsageHours         int `yaml:"RAW_USAGE_HOURS"`          // 0 keeps usage records forever
+	HourlyUsageDays       int `yaml:"HOURLY_USAGE_DAYS"`        // 0 keeps hourly usage rollups forever
+	IntervalSeconds       int `yaml:"INTERVAL_SECONDS"`
+}
+
+// Default returns the configuration used when no configuration file is present
+func Default() Config {
+	return Config{
+		AllowMultipleIPs:           true,
+		AllowedTimeGapSeconds:      15,
+		NonceCacheSize:             100000,
+		NoncesPerKey:               1000,
+		TrustedProxyHeader:         "X-Forwarded-For",
+		RotationGracePeriodSeconds: 86400,
+		KeyFormat: KeyFormat{
+			Prefix: "akm",
+		},
+		Notifications: Notifications{
+			Channel:        NotificationChannelLog,
+			TimeoutSeconds: 10,
+		},
+		Storage: StorageConfig{
+			Driver:                  StorageDriverMemory,
+			Directory:               "data",
+			SnapshotIntervalSeconds: 300,
+			SnapshotEveryRecords:    10000,
+		},
+		Retention: Retention{
+			RawUsageHours:   24,
+			HourlyUsageDays: 7,
+			IntervalSeconds: 3600,
+		},
+	}
+}
+
+// NewConfig loads the configuration file named by API_KEY_MANAGER_CONFIG (or config/default.yaml),
+// falling back to the defaults when the file does not exist
+func NewConfig() (Config, error) {
+	path := os.Getenv(_configPathEnv)
+	if path == "" {
+		path = _defaultConfigPath
+	}
+	return Load(path)
+}
+
+// Load reads the YAML file at path on top of the default configuration. ADMIN_ROOT_TOKEN and
+// API_KEY_PEPPER take precedence over the root token and pepper in the file.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Printf("configuration file %s not found, using defaults", path)
+	case err != nil:
+		return Config{}, fmt.Errorf("failed to read configuration file %s: %w", path, err)
+	default:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+		}
+		if err := checkOrganizationLimits(data); err != nil {
+			return Config{}, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+	}
+
+	if rootToken := os.Getenv(_adminRootTokenEnv); rootToken != "" {
+		cfg.Auth.RootToken = rootToken
+	}
+	if pepper := os.Getenv(_secretPepperEnv); pepper != "" {
+		cfg.Secrets.Pepper = pepper
+	}
+
+	return cfg, nil
+}
+
+// checkOrganizationLimits refuses the organization rate limits and quotas earlier versions configured by
+// organization name. They are set on the organizations through the admin API now, ignoring them would
+// silently lift the limits.
+func checkOrganizationLimits(data []byte) error {
+	var organizationLimits struct {
+		RateLimits struct {
+			Organizations map[string]any `yaml:"ORGANIZATIONS"`
+		} `yaml:"RATE_LIMITS"`
+		Quotas struct {
+			Organizations map[string]any `yaml:"ORGANIZATIONS"`
+		} `yaml:"QUOTAS"`
+	}
+	if err := yaml.Unmarshal(data, &organizationLimits); err != nil {
+		return err
+	}
+	if len(organizationLimits.RateLimits.Organizations) > 0 {
+		return errors.New("RATE_LIMITS.ORGANIZATIONS is no longer supported, set the rate limits with PUT /orgs/{orgId}/limits")
+	}
+	if len(organizationLimits.Quotas.Organizations) > 0 {
+		return errors.New("QUOTAS.ORGANIZATIONS is no longer supported, set the quotas with PUT /orgs/{orgId}/limits")
+	}
+	return nil
+}
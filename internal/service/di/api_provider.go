@@ -2,12 +2,35 @@ package di
 
 import (
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
+	"github.com/csherida/api-key-manager-service/internal/service/config"
 	"github.com/google/wire"
 )
 
 var ApiProvider = wire.NewSet(
+	NewClientIPResolver,
+	NewAdminAuthMiddleware,
+	api.NewAdminTokenHandler,
+	api.NewOrganizationHandler,
+	api.NewOrganizationApiKeyHandler,
 	api.NewApiKeyGeneratorHandler,
 	api.NewApiKeyValidationHandler,
 	api.NewApiKeyDeletionHandler,
 	api.NewApiKeyListHandler,
+	api.NewApiKeyDetailsHandler,
+	api.NewApiKeyIPBindingHandler,
+	api.NewApiKeyNetworksHandler,
+	api.NewApiKeyRateLimitHandler,
+	api.NewApiKeySuspensionHandler,
+	api.NewApiKeyPurgeHandler,
+	api.NewApiKeyRotationHandler,
+	api.NewApiKeyLeakHandler,
+	api.NewAuditHandler,
 )
+
+func NewAdminAuthMiddleware(cfg config.Config, authenticator api.AdminAuthenticator) api.AdminAuthMiddleware {
+	return api.NewAdminAuthMiddleware(authenticator, cfg.Auth.RequireGatewayToken)
+}
+
+func NewClientIPResolver(cfg config.Config) (api.ClientIPResolver, error) {
+	return api.NewClientIPResolver(cfg.TrustedProxies, cfg.TrustedProxyHeader)
+}
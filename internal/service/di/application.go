@@ -3,6 +3,7 @@ package di
 import (
 	"context"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -23,6 +24,34 @@ type Application struct {
 	keyValidationHandler func(http.ResponseWriter, *http.Request)
 	keyDeletionHandler   func(http.ResponseWriter, *http.Request)
 	keyListHandler       func(http.ResponseWriter, *http.Request)
+	keyStaleHandler      func(http.ResponseWriter, *http.Request)
+	keyGetHandler        func(http.ResponseWriter, *http.Request)
+	keyUpdateHandler     func(http.ResponseWriter, *http.Request)
+	keyUsageHandler      func(http.ResponseWriter, *http.Request)
+	keyIPBindingHandler  func(http.ResponseWriter, *http.Request)
+	keyNetworksHandler   func(http.ResponseWriter, *http.Request)
+	keyRateLimitHandler  func(http.ResponseWriter, *http.Request)
+	keySuspendHandler    func(http.ResponseWriter, *http.Request)
+	keyResumeHandler     func(http.ResponseWriter, *http.Request)
+	keyPurgeHandler      func(http.ResponseWriter, *http.Request)
+	keyRotationHandler   func(http.ResponseWriter, *http.Request)
+	keyLeakHandler       func(http.ResponseWriter, *http.Request)
+	auditEventList       func(http.ResponseWriter, *http.Request)
+	adminTokenCreate     func(http.ResponseWriter, *http.Request)
+	adminTokenList       func(http.ResponseWriter, *http.Request)
+	adminTokenRevoke     func(http.ResponseWriter, *http.Request)
+	orgCreate            func(http.ResponseWriter, *http.Request)
+	orgList              func(http.ResponseWriter, *http.Request)
+	orgGet               func(http.ResponseWriter, *http.Request)
+	orgUpdate            func(http.ResponseWriter, *http.Request)
+	orgLimits            func(http.ResponseWriter, *http.Request)
+	orgSuspend           func(http.ResponseWriter, *http.Request)
+	orgResume            func(http.ResponseWriter, *http.Request)
+	orgKeyGenerate       func(http.ResponseWriter, *http.Request)
+	orgKeyList           func(http.ResponseWriter, *http.Request)
+	orgKeyExpire         func(http.ResponseWriter, *http.Request)
+	orgKeyPurge          func(http.ResponseWriter, *http.Request)
+	adminAuth            api.AdminAuthMiddleware
 	repo                 usecase.Repository
 }
 
@@ -32,6 +61,19 @@ func NewApplication(
 	keyValidationHandler api.ApiKeyValidationHandler,
 	keyDeletionHandler api.ApiKeyDeletionHandler,
 	keyListHandler api.ApiKeyListHandler,
+	keyDetailsHandler api.ApiKeyDetailsHandler,
+	keyIPBindingHandler api.ApiKeyIPBindingHandler,
+	keyNetworksHandler api.ApiKeyNetworksHandler,
+	keyRateLimitHandler api.ApiKeyRateLimitHandler,
+	keySuspensionHandler api.ApiKeySuspensionHandler,
+	keyPurgeHandler api.ApiKeyPurgeHandler,
+	keyRotationHandler api.ApiKeyRotationHandler,
+	keyLeakHandler api.ApiKeyLeakHandler,
+	auditHandler api.AuditHandler,
+	adminTokenHandler api.AdminTokenHandler,
+	organizationHandler api.OrganizationHandler,
+	organizationApiKeyHandler api.OrganizationApiKeyHandler,
+	adminAuth api.AdminAuthMiddleware,
 ) Application {
 	appCtx, cancel := context.WithCancel(ctx)
 	app := Application{
@@ -41,6 +83,34 @@ func NewApplication(
 		keyValidationHandler: keyValidationHandler.ValidateApiKey,
 		keyDeletionHandler:   keyDeletionHandler.DeleteApiKey,
 		keyListHandler:       keyListHandler.ListApiKeys,
+		keyStaleHandler:      keyListHandler.ListStaleApiKeys,
+		keyGetHandler:        keyDetailsHandler.GetApiKey,
+		keyUpdateHandler:     keyDetailsHandler.UpdateApiKey,
+		keyUsageHandler:      keyDetailsHandler.GetApiKeyUsage,
+		keyIPBindingHandler:  keyIPBindingHandler.ResetIPBinding,
+		keyNetworksHandler:   keyNetworksHandler.UpdateNetworks,
+		keyRateLimitHandler:  keyRateLimitHandler.UpdateRateLimit,
+		keySuspendHandler:    keySuspensionHandler.SuspendApiKey,
+		keyResumeHandler:     keySuspensionHandler.ResumeApiKey,
+		keyPurgeHandler:      keyPurgeHandler.PurgeApiKey,
+		keyRotationHandler:   keyRotationHandler.RotateApiKey,
+		keyLeakHandler:       keyLeakHandler.ReportLeak,
+		auditEventList:       auditHandler.ListAuditEvents,
+		adminTokenCreate:     adminTokenHandler.CreateAdminToken,
+		adminTokenList:       adminTokenHandler.ListAdminTokens,
+		adminTokenRevoke:     adminTokenHandler.RevokeAdminToken,
+		orgCreate:            organizationHandler.CreateOrganization,
+		orgList:              organizationHandler.ListOrganizations,
+		orgGet:               organizationHandler.GetOrganization,
+		orgUpdate:            organizationHandler.UpdateOrganization,
+		orgLimits:            organizationHandler.UpdateOrganizationLimits,
+		orgSuspend:           organizationHandler.SuspendOrganization,
+		orgResume:            organizationHandler.ResumeOrganization,
+		orgKeyGenerate:       organizationApiKeyHandler.GenerateApiKey,
+		orgKeyList:           organizationApiKeyHandler.ListApiKeys,
+		orgKeyExpire:         organizationApiKeyHandler.ExpireApiKey,
+		orgKeyPurge:          keyPurgeHandler.PurgeOrganizationApiKeys,
+		adminAuth:            adminAuth,
 	}
 	return app
 }
@@ -65,15 +135,58 @@ func NewContext() context.Context {
 func (app *Application) Run() error {
 	///TODO: move implementation to infra folder and better server handling
 	router := mux.NewRouter()
-	router.HandleFunc("/keys", app.keyListHandler).Methods("GET")
-	router.HandleFunc("/keys", app.keyGeneratorHandler).Methods("POST")
-	router.HandleFunc("/keys/{keyId}", app.keyDeletionHandler).Methods("DELETE")
-	router.HandleFunc("/keys/validate", app.keyValidationHandler).Methods("POST")
+	router.Handle("/keys", app.requireRole(domain.AdminRoleViewer, app.keyListHandler)).Methods("GET")
+	router.Handle("/keys", app.requireRole(domain.AdminRoleIssuer, app.keyGeneratorHandler)).Methods("POST")
+	router.Handle("/keys/validate", app.adminAuth.RequireGateway()(http.HandlerFunc(app.keyValidationHandler))).Methods("POST")
+	router.Handle("/keys/stale", app.requireRole(domain.AdminRoleViewer, app.keyStaleHandler)).Methods("GET")
+	router.Handle("/keys/report-leak", app.requireRole(domain.AdminRoleRevoker, app.keyLeakHandler)).Methods("POST")
+	router.Handle("/keys/{keyId}", app.requireRole(domain.AdminRoleViewer, app.keyGetHandler)).Methods("GET")
+	router.Handle("/keys/{keyId}", app.requireRole(domain.AdminRoleIssuer, app.keyUpdateHandler)).Methods("PATCH")
+	router.Handle("/keys/{keyId}", app.requireRole(domain.AdminRoleRevoker, app.keyDeletionHandler)).Methods("DELETE")
+	router.Handle("/keys/{keyId}/usage", app.requireRole(domain.AdminRoleViewer, app.keyUsageHandler)).Methods("GET")
+	router.Handle("/keys/{keyId}/ip-binding", app.requireRole(domain.AdminRoleIssuer, app.keyIPBindingHandler)).Methods("DELETE")
+	router.Handle("/keys/{keyId}/networks", app.requireRole(domain.AdminRoleIssuer, app.keyNetworksHandler)).Methods("PUT")
+	router.Handle("/keys/{keyId}/rate-limit", app.requireRole(domain.AdminRoleIssuer, app.keyRateLimitHandler)).Methods("PUT")
+	router.Handle("/keys/{keyId}/suspend", app.requireRole(domain.AdminRoleRevoker, app.keySuspendHandler)).Methods("POST")
+	router.Handle("/keys/{keyId}/resume", app.requireRole(domain.AdminRoleRevoker, app.keyResumeHandler)).Methods("POST")
+	router.Handle("/keys/{keyId}/purge", app.requireRole(domain.AdminRoleAdmin, app.keyPurgeHandler)).Methods("POST")
+	router.Handle("/keys/{keyId}/rotate", app.requireRole(domain.AdminRoleIssuer, app.keyRotationHandler)).Methods("POST")
+	router.Handle("/orgs", app.requireRole(domain.AdminRoleViewer, app.orgList)).Methods("GET")
+	router.Handle("/orgs", app.requireRole(domain.AdminRoleIssuer, app.orgCreate)).Methods("POST")
+	router.Handle("/orgs/{orgId}", app.requireRole(domain.AdminRoleViewer, app.orgGet)).Methods("GET")
+	router.Handle("/orgs/{orgId}", app.requireRole(domain.AdminRoleIssuer, app.orgUpdate)).Methods("PATCH")
+	router.Handle("/orgs/{orgId}/limits", app.requireRole(domain.AdminRoleIssuer, app.orgLimits)).Methods("PUT")
+	router.Handle("/orgs/{orgId}/suspend", app.requireRole(domain.AdminRoleRevoker, app.orgSuspend)).Methods("POST")
+	router.Handle("/orgs/{orgId}/resume", app.requireRole(domain.AdminRoleRevoker, app.orgResume)).Methods("POST")
+	router.Handle("/orgs/{orgId}/keys", app.requireOrganizationRole(domain.AdminRoleViewer, app.orgKeyList)).Methods("GET")
+	router.Handle("/orgs/{orgId}/keys", app.requireOrganizationRole(domain.AdminRoleIssuer, app.orgKeyGenerate)).Methods("POST")
+	router.Handle("/orgs/{orgId}/keys/purge", app.requireRole(domain.AdminRoleAdmin, app.orgKeyPurge)).Methods("POST")
+	router.Handle("/orgs/{orgId}/keys/{keyId}", app.requireOrganizationRole(domain.AdminRoleRevoker, app.orgKeyExpire)).Methods("DELETE")
+	router.Handle("/audit/events", app.requireRole(domain.AdminRoleViewer, app.auditEventList)).Methods("GET")
+	router.Handle("/admin/tokens", app.requireRole(domain.AdminRoleAdmin, app.adminTokenList)).Methods("GET")
+	router.Handle("/admin/tokens", app.requireRole(domain.AdminRoleAdmin, app.adminTokenCreate)).Methods("POST")
+	router.Handle("/admin/tokens/{tokenId}", app.requireRole(domain.AdminRoleAdmin, app.adminTokenRevoke)).Methods("DELETE")
 
 	corsHandler := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:" + strconv.Itoa(_serverPort)},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedOrigins: []string{"http://localhost:" + strconv.Itoa(_serverPort)},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{
+			"Content-Type",
+			"Authorization",
+			api.TimestampHeader,
+			api.NonceHeader,
+			api.SignedMethodHeader,
+			api.SignedPathHeader,
+			api.SignedBodySha256Header,
+			api.GatewayTokenHeader,
+		},
+		ExposedHeaders: []string{
+			api.RateLimitLimitHeader,
+			api.RateLimitRemainingHeader,
+			api.RateLimitResetHeader,
+			api.QuotaExceededHeader,
+			"Retry-After",
+		},
 		AllowCredentials: true,
 	})
 
@@ -103,6 +216,17 @@ func (app *Application) Run() error {
 	return nil
 }
 
+// requireRole guards a management endpoint with an admin token holding role
+func (app *Application) requireRole(role string, handler func(http.ResponseWriter, *http.Request)) http.Handler {
+	return app.adminAuth.RequireRole(role)(http.HandlerFunc(handler))
+}
+
+// requireOrganizationRole guards an organization scoped endpoint with a platform token holding role or
+// an org_admin token of the organization
+func (app *Application) requireOrganizationRole(role string, handler func(http.ResponseWriter, *http.Request)) http.Handler {
+	return app.adminAuth.RequireOrganizationRole(role)(http.HandlerFunc(handler))
+}
+
 func (app *Application) CancelContext() {
 	app.cancel()
 }
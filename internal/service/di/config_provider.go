@@ -0,0 +1,10 @@
+package di
+
+import (
+	"github.com/csherida/api-key-manager-service/internal/service/config"
+	"github.com/google/wire"
+)
+
+var ConfigProvider = wire.NewSet(
+	config.NewConfig,
+)
@@ -0,0 +1,30 @@
+package di
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/csherida/api-key-manager-service/internal/service/config"
+	"github.com/google/wire"
+)
+
+var NotificationProvider = wire.NewSet( //nolint:gochecknoglobals
+	NewNotifier,
+)
+
+// NewNotifier selects the Notifier implementation configured under NOTIFICATIONS.CHANNEL
+func NewNotifier(cfg config.Config) (usecase.Notifier, error) {
+	switch cfg.Notifications.Channel {
+	case "", config.NotificationChannelLog:
+		return infra.NewLogNotifier(), nil
+	case config.NotificationChannelWebhook:
+		if cfg.Notifications.WebhookUrl == "" {
+			return nil, fmt.Errorf("NOTIFICATIONS.WEBHOOK_URL is required for the %s channel", config.NotificationChannelWebhook)
+		}
+		return infra.NewWebhookNotifier(cfg.Notifications.WebhookUrl, time.Duration(cfg.Notifications.TimeoutSeconds)*time.Second), nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel %q", cfg.Notifications.Channel)
+	}
+}
@@ -1,12 +1,32 @@
 package di
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/csherida/api-key-manager-service/internal/service/config"
 	"github.com/google/wire"
 )
 
 var StorageProvider = wire.NewSet( //nolint:gochecknoglobals
-	infra.NewDataStore,
-	wire.Bind(new(usecase.Repository), new(*infra.DataStore)),
+	NewRepository,
 )
+
+// NewRepository selects the Repository implementation configured under STORAGE.DRIVER
+func NewRepository(ctx context.Context, cfg config.Config) (usecase.Repository, error) {
+	switch cfg.Storage.Driver {
+	case "", config.StorageDriverMemory:
+		return infra.NewDataStore(), nil
+	case config.StorageDriverFile:
+		return infra.NewFileStore(ctx, infra.FileStoreOptions{
+			Directory:            cfg.Storage.Directory,
+			SnapshotInterval:     time.Duration(cfg.Storage.SnapshotIntervalSeconds) * time.Second,
+			SnapshotEveryRecords: cfg.Storage.SnapshotEveryRecords,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
@@ -1,18 +1,154 @@
 package di
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/csherida/api-key-manager-service/internal/service/config"
+	"github.com/csherida/api-key-manager-service/pkg/keyformat"
 	"github.com/google/wire"
 )
 
+// _rootTokenFileName is the file in the storage directory a generated admin root token is written to
+const _rootTokenFileName = "root-token"
+
 var UseCaseProvider = wire.NewSet(
 	usecase.NewApiKeyGeneration,
 	wire.Bind(new(api.ApiKeyGenerator), new(usecase.ApiKeyGeneration)),
+	NewValidationPolicy,
+	NewSecretPolicy,
+	NewKeyFormatPolicy,
+	usecase.NewKeyAlgorithms,
+	usecase.NewRateLimiter,
 	usecase.NewApiKeyValidation,
 	wire.Bind(new(api.ApiKeyValidator), new(usecase.ApiKeyValidation)),
 	usecase.NewApiKeyDeletion,
 	wire.Bind(new(api.ApiKeyDeleter), new(usecase.ApiKeyDeletion)),
+	wire.Bind(new(api.OrganizationApiKeyDeleter), new(usecase.ApiKeyDeletion)),
 	usecase.NewApiKeyListing,
 	wire.Bind(new(api.ApiKeyLister), new(usecase.ApiKeyListing)),
+	wire.Bind(new(api.StaleApiKeyLister), new(usecase.ApiKeyListing)),
+	wire.Bind(new(api.OrganizationApiKeyLister), new(usecase.ApiKeyListing)),
+	wire.Bind(new(api.ApiKeyGetter), new(usecase.ApiKeyListing)),
+	wire.Bind(new(api.ApiKeyUsageGetter), new(usecase.ApiKeyListing)),
+	usecase.NewApiKeyUpdate,
+	wire.Bind(new(api.ApiKeyUpdater), new(usecase.ApiKeyUpdate)),
+	usecase.NewApiKeyIPBinding,
+	wire.Bind(new(api.ApiKeyIPBindingResetter), new(usecase.ApiKeyIPBinding)),
+	usecase.NewApiKeyNetworks,
+	wire.Bind(new(api.ApiKeyNetworksUpdater), new(usecase.ApiKeyNetworks)),
+	usecase.NewApiKeyRateLimits,
+	wire.Bind(new(api.ApiKeyRateLimitUpdater), new(usecase.ApiKeyRateLimits)),
+	usecase.NewApiKeySuspension,
+	wire.Bind(new(api.ApiKeySuspender), new(usecase.ApiKeySuspension)),
+	NewAdminPolicy,
+	usecase.NewAdminAuthentication,
+	wire.Bind(new(api.AdminAuthenticator), new(usecase.AdminAuthentication)),
+	usecase.NewAdminTokens,
+	wire.Bind(new(api.AdminTokenManager), new(usecase.AdminTokens)),
+	usecase.NewOrganizations,
+	wire.Bind(new(api.OrganizationManager), new(usecase.Organizations)),
+	NewRotationPolicy,
+	usecase.NewApiKeyRotation,
+	wire.Bind(new(api.ApiKeyRotator), new(usecase.ApiKeyRotation)),
+	usecase.NewApiKeyLeakReporting,
+	wire.Bind(new(api.ApiKeyLeakReporter), new(usecase.ApiKeyLeakReporting)),
+	NewRetentionPolicy,
+	NewApiKeyPurge,
+	wire.Bind(new(api.ApiKeyPurger), new(usecase.ApiKeyPurge)),
+	usecase.NewAuditTrail,
+	wire.Bind(new(api.AuditEventLister), new(usecase.AuditTrail)),
 )
+
+func NewValidationPolicy(cfg config.Config) usecase.ValidationPolicy {
+	var defaultRateLimit *domain.RateLimit
+	if cfg.RateLimits.Default != nil {
+		rateLimit := toDomainRateLimit(*cfg.RateLimits.Default)
+		defaultRateLimit = &rateLimit
+	}
+
+	return usecase.ValidationPolicy{
+		AllowMultipleIPs: cfg.AllowMultipleIPs,
+		AllowedTimeGap:   time.Duration(cfg.AllowedTimeGapSeconds) * time.Second,
+		NonceCacheSize:   cfg.NonceCacheSize,
+		NoncesPerKey:     cfg.NoncesPerKey,
+		DefaultRateLimit: defaultRateLimit,
+	}
+}
+
+func NewRotationPolicy(cfg config.Config) usecase.RotationPolicy {
+	return usecase.RotationPolicy{
+		DefaultGracePeriod: time.Duration(cfg.RotationGracePeriodSeconds) * time.Second,
+	}
+}
+
+func NewRetentionPolicy(cfg config.Config) usecase.RetentionPolicy {
+	return usecase.RetentionPolicy{
+		PurgeRevokedAfter: time.Duration(cfg.Retention.PurgeRevokedAfterDays) * 24 * time.Hour,
+		RawUsage:          time.Duration(cfg.Retention.RawUsageHours) * time.Hour,
+		HourlyUsage:       time.Duration(cfg.Retention.HourlyUsageDays) * 24 * time.Hour,
+		Interval:          time.Duration(cfg.Retention.IntervalSeconds) * time.Second,
+	}
+}
+
+// NewApiKeyPurge also starts the retention job when revoked keys are to be purged or usage pruned, which
+// stops with ctx
+func NewApiKeyPurge(ctx context.Context, repo usecase.Repository, policy usecase.RetentionPolicy, limiter *usecase.RateLimiter) (usecase.ApiKeyPurge, error) {
+	purge := usecase.NewApiKeyPurge(repo, policy, limiter)
+	if policy.IsEnabled() {
+		if policy.Interval <= 0 {
+			return usecase.ApiKeyPurge{}, errors.New("RETENTION.INTERVAL_SECONDS must be positive")
+		}
+		go purge.RunRetention(ctx)
+	}
+	return purge, nil
+}
+
+// NewSecretPolicy uses the configured pepper. Only the memory store, which forgets its keys on restart
+// anyway, falls back to a random pepper that lives as long as the process; a durable store would keep
+// opaque key hashes that no longer verify after a restart.
+func NewSecretPolicy(cfg config.Config) (usecase.SecretPolicy, error) {
+	if cfg.Secrets.Pepper != "" {
+		return usecase.SecretPolicy{Pepper: []byte(cfg.Secrets.Pepper)}, nil
+	}
+	if cfg.Storage.Driver != "" && cfg.Storage.Driver != config.StorageDriverMemory {
+		return usecase.SecretPolicy{}, fmt.Errorf("SECRETS.PEPPER or API_KEY_PEPPER is required with the %s storage driver", cfg.Storage.Driver)
+	}
+
+	pepper := make([]byte, 32)
+	if _, err := rand.Read(pepper); err != nil {
+		return usecase.SecretPolicy{}, fmt.Errorf("failed to generate secret pepper: %w", err)
+	}
+	log.Println("WARNING: no secret pepper configured, using a random one for the memory store")
+	return usecase.SecretPolicy{Pepper: pepper}, nil
+}
+
+func NewKeyFormatPolicy(cfg config.Config) (usecase.KeyFormatPolicy, error) {
+	if err := keyformat.ValidateLabel(cfg.KeyFormat.Prefix); err != nil {
+		return usecase.KeyFormatPolicy{}, fmt.Errorf("invalid KEY_FORMAT.PREFIX: %w", err)
+	}
+	return usecase.KeyFormatPolicy{Prefix: cfg.KeyFormat.Prefix}, nil
+}
+
+func NewAdminPolicy(cfg config.Config) usecase.AdminPolicy {
+	return usecase.AdminPolicy{
+		RootToken:     cfg.Auth.RootToken,
+		RootTokenFile: filepath.Join(cfg.Storage.Directory, _rootTokenFileName),
+	}
+}
+
+func toDomainRateLimit(rateLimit config.RateLimit) domain.RateLimit {
+	return domain.RateLimit{
+		RequestsPerSecond: rateLimit.RequestsPerSecond,
+		RequestsPerMinute: rateLimit.RequestsPerMinute,
+		Burst:             rateLimit.Burst,
+	}
+}
@@ -8,8 +8,8 @@ package di
 
 import (
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
-	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/csherida/api-key-manager-service/internal/service/config"
 )
 
 // Injectors from wire_inject.go:
@@ -17,15 +17,75 @@ import (
 // SetupApplication is where we define the dependencies wire will inject
 func SetupApplication() (Application, error) {
 	context := NewContext()
-	dataStore := infra.NewDataStore()
-	apiKeyGeneration := usecase.NewApiKeyGeneration(dataStore)
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return Application{}, err
+	}
+	repository, err := NewRepository(context, configConfig)
+	if err != nil {
+		return Application{}, err
+	}
+	secretPolicy, err := NewSecretPolicy(configConfig)
+	if err != nil {
+		return Application{}, err
+	}
+	keyFormatPolicy, err := NewKeyFormatPolicy(configConfig)
+	if err != nil {
+		return Application{}, err
+	}
+	keyAlgorithms := usecase.NewKeyAlgorithms(secretPolicy, keyFormatPolicy)
+	apiKeyGeneration := usecase.NewApiKeyGeneration(repository, keyAlgorithms)
 	apiKeyGeneratorHandler := api.NewApiKeyGeneratorHandler(apiKeyGeneration)
-	apiKeyValidation := usecase.NewApiKeyValidation(dataStore)
-	apiKeyValidationHandler := api.NewApiKeyValidationHandler(apiKeyValidation)
-	apiKeyDeletion := usecase.NewApiKeyDeletion(dataStore)
+	validationPolicy := NewValidationPolicy(configConfig)
+	rateLimiter := usecase.NewRateLimiter()
+	apiKeyValidation := usecase.NewApiKeyValidation(repository, validationPolicy, keyAlgorithms, rateLimiter)
+	clientIPResolver, err := NewClientIPResolver(configConfig)
+	if err != nil {
+		return Application{}, err
+	}
+	apiKeyValidationHandler := api.NewApiKeyValidationHandler(apiKeyValidation, clientIPResolver)
+	apiKeyDeletion := usecase.NewApiKeyDeletion(repository)
 	apiKeyDeletionHandler := api.NewApiKeyDeletionHandler(apiKeyDeletion)
-	apiKeyListing := usecase.NewApiKeyListing(dataStore)
-	apiKeyListHandler := api.NewApiKeyListHandler(apiKeyListing)
-	application := NewApplication(context, apiKeyGeneratorHandler, apiKeyValidationHandler, apiKeyDeletionHandler, apiKeyListHandler)
+	apiKeyListing := usecase.NewApiKeyListing(repository)
+	apiKeyListHandler := api.NewApiKeyListHandler(apiKeyListing, apiKeyListing)
+	apiKeyUpdate := usecase.NewApiKeyUpdate(repository)
+	apiKeyDetailsHandler := api.NewApiKeyDetailsHandler(apiKeyListing, apiKeyUpdate, apiKeyListing)
+	apiKeyIPBinding := usecase.NewApiKeyIPBinding(repository)
+	apiKeyIPBindingHandler := api.NewApiKeyIPBindingHandler(apiKeyIPBinding)
+	apiKeyNetworks := usecase.NewApiKeyNetworks(repository)
+	apiKeyNetworksHandler := api.NewApiKeyNetworksHandler(apiKeyNetworks)
+	apiKeyRateLimits := usecase.NewApiKeyRateLimits(repository)
+	apiKeyRateLimitHandler := api.NewApiKeyRateLimitHandler(apiKeyRateLimits)
+	apiKeySuspension := usecase.NewApiKeySuspension(repository)
+	apiKeySuspensionHandler := api.NewApiKeySuspensionHandler(apiKeySuspension)
+	retentionPolicy := NewRetentionPolicy(configConfig)
+	apiKeyPurge, err := NewApiKeyPurge(context, repository, retentionPolicy, rateLimiter)
+	if err != nil {
+		return Application{}, err
+	}
+	apiKeyPurgeHandler := api.NewApiKeyPurgeHandler(apiKeyPurge)
+	rotationPolicy := NewRotationPolicy(configConfig)
+	apiKeyRotation := usecase.NewApiKeyRotation(repository, rotationPolicy, keyAlgorithms)
+	apiKeyRotationHandler := api.NewApiKeyRotationHandler(apiKeyRotation)
+	notifier, err := NewNotifier(configConfig)
+	if err != nil {
+		return Application{}, err
+	}
+	apiKeyLeakReporting := usecase.NewApiKeyLeakReporting(repository, keyAlgorithms, apiKeyDeletion, notifier)
+	apiKeyLeakHandler := api.NewApiKeyLeakHandler(apiKeyLeakReporting)
+	auditTrail := usecase.NewAuditTrail(repository)
+	auditHandler := api.NewAuditHandler(auditTrail)
+	adminTokens := usecase.NewAdminTokens(repository)
+	adminTokenHandler := api.NewAdminTokenHandler(adminTokens)
+	organizations := usecase.NewOrganizations(repository)
+	organizationHandler := api.NewOrganizationHandler(organizations)
+	organizationApiKeyHandler := api.NewOrganizationApiKeyHandler(apiKeyGeneration, apiKeyListing, apiKeyDeletion)
+	adminPolicy := NewAdminPolicy(configConfig)
+	adminAuthentication, err := usecase.NewAdminAuthentication(repository, adminPolicy)
+	if err != nil {
+		return Application{}, err
+	}
+	adminAuthMiddleware := NewAdminAuthMiddleware(configConfig, adminAuthentication)
+	application := NewApplication(context, apiKeyGeneratorHandler, apiKeyValidationHandler, apiKeyDeletionHandler, apiKeyListHandler, apiKeyDetailsHandler, apiKeyIPBindingHandler, apiKeyNetworksHandler, apiKeyRateLimitHandler, apiKeySuspensionHandler, apiKeyPurgeHandler, apiKeyRotationHandler, apiKeyLeakHandler, auditHandler, adminTokenHandler, organizationHandler, organizationApiKeyHandler, adminAuthMiddleware)
 	return application, nil
 }
@@ -9,7 +9,9 @@ import "github.com/google/wire"
 func SetupApplication() (Application, error) {
 	panic(wire.Build(wire.NewSet(
 		ApiProvider,
+		ConfigProvider,
 		ContextProvider,
+		NotificationProvider,
 		StorageProvider,
 		UseCaseProvider,
 		wire.NewSet(NewApplication),
@@ -0,0 +1,187 @@
+// Package keyformat encodes API keys in a recognizable, checksummed format so secret scanners and log
+// redactors can find leaked keys without calling the service:
+//
+//	<prefix>_<environment>_<secret>_<checksum>
+//
+// for example akm_live_3fa1c2d4e5b6.kQ7vZ..._1a2B3c. The checksum is the CRC32 of everything before it,
+// base62 encoded to six characters, so typos and random strings are rejected without any lookup.
+package keyformat
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	_separator      = "_"
+	_checksumLength = 6
+	_base62         = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	_labelPattern    = `[a-z0-9]+`
+	_secretPattern   = `[A-Za-z0-9._-]+`
+	_checksumPattern = `[0-9A-Za-z]{6}`
+)
+
+var (
+	// ErrMalformed is returned for strings that do not have the shape of a key
+	ErrMalformed = errors.New("malformed API key")
+	// ErrChecksumMismatch is returned for keys whose checksum does not match, e.g. because of a typo
+	ErrChecksumMismatch = errors.New("API key checksum mismatch")
+
+	_labelRegexp  = regexp.MustCompile(`^` + _labelPattern + `$`)
+	_secretRegexp = regexp.MustCompile(`^` + _secretPattern + `$`)
+	_keyRegexp    = regexp.MustCompile(`^(` + _labelPattern + `)_(` + _labelPattern + `)_(` + _secretPattern + `)_(` + _checksumPattern + `)$`)
+
+	// _findRegexps caches the pattern Find compiles for each prefix, scanners call it once per line
+	_findRegexps sync.Map
+)
+
+// Key is an API key split into its parts
+type Key struct {
+	Prefix      string // identifies the issuer, e.g. akm
+	Environment string // e.g. live
+	Secret      string // what the issuing service verifies
+}
+
+// New builds a key, checking that prefix and environment are lower case alphanumeric and the secret only
+// uses characters the format allows
+func New(prefix, environment, secret string) (Key, error) {
+	if err := ValidateLabel(prefix); err != nil {
+		return Key{}, fmt.Errorf("invalid prefix: %w", err)
+	}
+	if err := ValidateLabel(environment); err != nil {
+		return Key{}, fmt.Errorf("invalid environment: %w", err)
+	}
+	if !_secretRegexp.MatchString(secret) {
+		return Key{}, fmt.Errorf("%w: secret contains characters outside [A-Za-z0-9._-]", ErrMalformed)
+	}
+	return Key{Prefix: prefix, Environment: environment, Secret: secret}, nil
+}
+
+// ValidateLabel checks a prefix or environment marker is non-empty lower case alphanumeric
+func ValidateLabel(label string) error {
+	if !_labelRegexp.MatchString(label) {
+		return fmt.Errorf("%q must be lower case letters and digits", label)
+	}
+	return nil
+}
+
+// String encodes the key with its checksum
+func (k Key) String() string {
+	payload := k.Prefix + _separator + k.Environment + _separator + k.Secret
+	return payload + _separator + checksum(payload)
+}
+
+// Parse splits an encoded key into its parts and verifies its checksum
+func Parse(key string) (Key, error) {
+	parts := _keyRegexp.FindStringSubmatch(key)
+	if parts == nil {
+		return Key{}, ErrMalformed
+	}
+
+	payload := key[:len(key)-_checksumLength-len(_separator)]
+	if checksum(payload) != parts[4] {
+		return Key{}, ErrChecksumMismatch
+	}
+	return Key{Prefix: parts[1], Environment: parts[2], Secret: parts[3]}, nil
+}
+
+// LooksLikeKey reports whether s is meant to be an encoded key with the given prefix, whether or not it
+// is well formed. Services use it to tell encoded keys from keys issued before the format existed.
+func LooksLikeKey(s string, prefix string) bool {
+	return strings.HasPrefix(s, prefix+_separator)
+}
+
+// Find returns every key with the given prefix and a valid checksum found in text, in order of
+// appearance. Candidates failing the checksum are left out, which keeps false positives rare.
+func Find(text string, prefix string) []string {
+	candidates := findRegexp(prefix).FindAllString(text, -1)
+
+	var keys []string
+	for _, candidate := range candidates {
+		if key, err := Parse(candidate); err == nil && key.Prefix == prefix {
+			keys = append(keys, candidate)
+			continue
+		}
+		keys = append(keys, splitCandidate(candidate, prefix)...)
+	}
+	return keys
+}
+
+// splitCandidate returns the keys within a candidate that is no key as a whole. Secrets may contain the
+// characters joining keys in a text, so keys joined by '-', '.' or '_' match as one candidate; each of
+// them ends at a checksum followed by a joining character or the end of the candidate.
+func splitCandidate(candidate string, prefix string) []string {
+	var keys []string
+	for start := 0; start >= 0; {
+		end := keyEnd(candidate[start:], prefix)
+		if end < 0 {
+			start = nextKeyStart(candidate, prefix, start+1)
+			continue
+		}
+		keys = append(keys, candidate[start:start+end])
+		start = nextKeyStart(candidate, prefix, start+end)
+	}
+	return keys
+}
+
+// keyEnd returns the length of the shortest key with the given prefix s starts with, or -1 if there is none
+func keyEnd(s string, prefix string) int {
+	for i := len(prefix) + len(_separator); i+len(_separator)+_checksumLength <= len(s); i++ {
+		end := i + len(_separator) + _checksumLength
+		if !strings.HasPrefix(s[i:], _separator) || (end < len(s) && !isJoining(s[end])) {
+			continue
+		}
+		if key, err := Parse(s[:end]); err == nil && key.Prefix == prefix {
+			return end
+		}
+	}
+	return -1
+}
+
+// nextKeyStart returns the index of the next key with the given prefix in candidate from index from on, or
+// -1 if there is none. Like in the text, a key only starts after a joining character.
+func nextKeyStart(candidate string, prefix string, from int) int {
+	for from < len(candidate) {
+		i := strings.Index(candidate[from:], prefix+_separator)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if i > 0 && isJoining(candidate[i-1]) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+// isJoining reports whether c may join keys in a text while also being allowed in a secret
+func isJoining(c byte) bool {
+	return c == '-' || c == '.' || c == '_'
+}
+
+// findRegexp returns the pattern matching keys with the given prefix anywhere in a text
+func findRegexp(prefix string) *regexp.Regexp {
+	if cached, ok := _findRegexps.Load(prefix); ok {
+		return cached.(*regexp.Regexp)
+	}
+	compiled := regexp.MustCompile(`\b` + regexp.QuoteMeta(prefix) + `_` + _labelPattern + `_` + _secretPattern + `_` + _checksumPattern + `\b`)
+	cached, _ := _findRegexps.LoadOrStore(prefix, compiled)
+	return cached.(*regexp.Regexp)
+}
+
+// checksum is the CRC32 of payload in base62, left padded to a fixed length
+func checksum(payload string) string {
+	sum := crc32.ChecksumIEEE([]byte(payload))
+	encoded := make([]byte, _checksumLength)
+	for i := _checksumLength - 1; i >= 0; i-- {
+		encoded[i] = _base62[sum%62]
+		sum /= 62
+	}
+	return string(encoded)
+}
@@ -0,0 +1,47 @@
+//go:build e2e
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedRootTokenStaysOutOfTheLog(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "data", "root-token")
+	require.NoError(t, os.MkdirAll(filepath.Dir(tokenFile), 0o700))
+	// A token file left readable by an earlier run is replaced, not reused with its mode
+	require.NoError(t, os.WriteFile(tokenFile, []byte("stale\n"), 0o644))
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	authentication, err := usecase.NewAdminAuthentication(infra.NewDataStore(), usecase.AdminPolicy{RootTokenFile: tokenFile})
+	require.NoError(t, err)
+
+	info, err := os.Stat(tokenFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err := os.ReadFile(tokenFile)
+	require.NoError(t, err)
+	rootToken := strings.TrimSpace(string(data))
+	require.NotEqual(t, "stale", rootToken)
+
+	require.NotContains(t, logged.String(), rootToken)
+	require.Contains(t, logged.String(), tokenFile)
+
+	adminToken, err := authentication.Authenticate(context.Background(), rootToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{domain.AdminRoleAdmin}, adminToken.Roles)
+}
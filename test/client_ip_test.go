@@ -0,0 +1,62 @@
+//go:build e2e
+
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIPResolverReadsOnlyTrustedHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "spoofed Forwarded is ignored when the proxy sets X-Forwarded-For",
+			header:  api.XForwardedForHeader,
+			headers: map[string]string{"Forwarded": "for=1.2.3.4", "X-Real-IP": "5.6.7.8", "X-Forwarded-For": "203.0.113.5"},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "spoofed headers without the trusted one leave the proxy as the client",
+			header:  api.XForwardedForHeader,
+			headers: map[string]string{"Forwarded": "for=1.2.3.4", "X-Real-IP": "5.6.7.8"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "Forwarded is read when configured",
+			header:  "forwarded",
+			headers: map[string]string{"Forwarded": `for="[2001:db8::7]:4711"`, "X-Forwarded-For": "1.2.3.4"},
+			want:    "2001:db8::7",
+		},
+		{
+			name:    "X-Real-IP is read when configured",
+			header:  api.XRealIPHeader,
+			headers: map[string]string{"X-Real-IP": "198.51.100.3", "X-Forwarded-For": "1.2.3.4"},
+			want:    "198.51.100.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := api.NewClientIPResolver([]string{"10.0.0.1"}, tt.header)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("POST", "/keys/validate", nil)
+			req.RemoteAddr = "10.0.0.1:51234"
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			require.Equal(t, tt.want, resolver.ClientIP(req))
+		})
+	}
+
+	_, err := api.NewClientIPResolver(nil, "X-Client-IP")
+	require.Error(t, err)
+}
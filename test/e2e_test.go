@@ -4,20 +4,35 @@ package test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/api"
 	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
 	"github.com/csherida/api-key-manager-service/internal/service/di"
+	"github.com/csherida/api-key-manager-service/pkg/keyformat"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 	"io"
 	"log"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
 
+const _adminRootToken = "e2e-admin-root-token"
+
 func TestApiKeyManager(t *testing.T) {
+	t.Setenv("ADMIN_ROOT_TOKEN", _adminRootToken)
+
 	application, err := di.SetupApplication()
 	if err != nil {
 		t.Fatalf("failed to setup application: %v", err)
@@ -56,10 +71,57 @@ func TestApiKeyManager(t *testing.T) {
 			}
 		})
 
+		// Test signed request validation, which never sends the key itself
+		t.Run("TestSignedApiKeyValidation", func(t *testing.T) {
+			body := []byte(`{"order_id": 42}`)
+			digest := sha256.Sum256(body)
+			signedRequest := domain.SignedRequest{
+				Method:     "POST",
+				Path:       "/keys/validate",
+				Timestamp:  time.Now().Unix(),
+				Nonce:      "nonce-1",
+				BodySha256: hex.EncodeToString(digest[:]),
+			}
+
+			validationResponse, statusCode := validateSignedRequest(t, apiKeyResponse2.ApiKey, signedRequest, body)
+			require.Equal(t, http.StatusOK, statusCode)
+			require.True(t, validationResponse.Valid)
+			require.Equal(t, apiKeyResponse2.ApiId, validationResponse.ApiId)
+
+			// Reusing the nonce within the time gap is a replay
+			validationResponse, statusCode = validateSignedRequest(t, apiKeyResponse2.ApiKey, signedRequest, body)
+			require.Equal(t, http.StatusUnauthorized, statusCode)
+			require.Equal(t, api.ErrorCodeReplayedRequest, validationResponse.ErrorCode)
+
+			// A timestamp outside the time gap is stale
+			staleRequest := signedRequest
+			staleRequest.Nonce = "nonce-2"
+			staleRequest.Timestamp = time.Now().Add(-time.Hour).Unix()
+			validationResponse, statusCode = validateSignedRequest(t, apiKeyResponse2.ApiKey, staleRequest, body)
+			require.Equal(t, http.StatusUnauthorized, statusCode)
+			require.Equal(t, api.ErrorCodeStaleRequest, validationResponse.ErrorCode)
+
+			// A signature over a different body must not validate
+			tamperedRequest := signedRequest
+			tamperedRequest.Nonce = "nonce-3"
+			signature, err := usecase.SignRequest(apiKeyResponse2.ApiKey, tamperedRequest)
+			require.NoError(t, err)
+			tampered, err := http.NewRequest("POST", "http://localhost:8080/keys/validate", bytes.NewReader([]byte(`{"order_id": 43}`)))
+			require.NoError(t, err)
+			tampered.Header.Add("Authorization", "Signature "+signature)
+			tampered.Header.Add(api.TimestampHeader, strconv.FormatInt(tamperedRequest.Timestamp, 10))
+			tampered.Header.Add(api.NonceHeader, tamperedRequest.Nonce)
+
+			tamperedResp, err := http.DefaultClient.Do(tampered)
+			require.NoError(t, err)
+			defer tamperedResp.Body.Close()
+			require.Equal(t, http.StatusUnauthorized, tamperedResp.StatusCode)
+		})
+
 		// Test API key listing
 		t.Run("TestApiKeyListing", func(t *testing.T) {
 			// Create a request to list all API keys
-			resp, err := http.Get("http://localhost:8080/keys")
+			resp, err := adminGet("http://localhost:8080/keys")
 			if err != nil {
 				t.Fatalf("failed to make GET request: %v", err)
 			}
@@ -111,6 +173,9 @@ func TestApiKeyManager(t *testing.T) {
 				if _, hasPublicKey := key["public_key"]; hasPublicKey {
 					t.Error("response should not contain public_key")
 				}
+				if _, hasSecretHash := key["secret_hash"]; hasSecretHash {
+					t.Error("response should not contain secret_hash")
+				}
 
 				// Verify required metadata fields
 				if _, hasOrgName := key["organization_name"]; !hasOrgName {
@@ -132,7 +197,7 @@ func TestApiKeyManager(t *testing.T) {
 			// Create a request to delete/expire the API key
 			client := &http.Client{}
 			deleteURL := fmt.Sprintf("http://localhost:8080/keys/%s", apiKeyResponse.ApiId)
-			req, err := http.NewRequest("DELETE", deleteURL, nil)
+			req, err := newAdminRequest("DELETE", deleteURL, nil)
 			if err != nil {
 				t.Fatalf("failed to create delete request: %v", err)
 			}
@@ -192,7 +257,7 @@ func TestApiKeyManager(t *testing.T) {
 			}
 
 			// Verify the key does not appear in GET /keys
-			resp, err = http.Get("http://localhost:8080/keys")
+			resp, err = adminGet("http://localhost:8080/keys")
 			if err != nil {
 				t.Fatalf("failed to make GET request: %v", err)
 			}
@@ -211,14 +276,1067 @@ func TestApiKeyManager(t *testing.T) {
 			}
 		})
 	})
+
+	// Test a key bound to an explicit IP
+	t.Run("TestApiKeyIPBinding", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			BoundIP:          "203.0.113.7",
+		})
+
+		validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusForbidden, statusCode)
+		require.Equal(t, api.ErrorCodeIPNotAllowed, validationResponse.ErrorCode)
+
+		req, err := newAdminRequest("DELETE", fmt.Sprintf("http://localhost:8080/keys/%s/ip-binding", apiKeyResponse.ApiId), nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		validationResponse, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, validationResponse.Valid)
+	})
+
+	// Test per-key CIDR allowlists and denylists
+	t.Run("TestApiKeyNetworks", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			DeniedCIDRs:      []string{"127.0.0.0/8", "::1"},
+		})
+
+		validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusForbidden, statusCode)
+		require.Equal(t, api.ErrorCodeIPNotAllowed, validationResponse.ErrorCode)
+
+		networks, err := json.Marshal(domain.ApiKeyNetworks{AllowedCIDRs: []string{"127.0.0.0/8", "::1/128"}})
+		require.NoError(t, err)
+		req, err := newAdminRequest("PUT", fmt.Sprintf("http://localhost:8080/keys/%s/networks", apiKeyResponse.ApiId), bytes.NewReader(networks))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		validationResponse, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, validationResponse.Valid)
+
+		apiKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, uint64(1), apiKey.UsageStats.TotalRequests)
+		require.Equal(t, uint64(1), apiKey.UsageStats.RejectedRequests)
+	})
+
+	// Test that forwarding headers from untrusted peers are ignored and ports are stripped
+	t.Run("TestClientIPExtraction", func(t *testing.T) {
+		apiKeyResponse := generateApiKey(t)
+
+		for _, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+			req, err := http.NewRequest("POST", "http://localhost:8080/keys/validate", nil)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", "Bearer "+apiKeyResponse.ApiKey)
+			req.Header.Add("X-Forwarded-For", spoofed)
+			req.Close = true // force a new connection, and so a new source port
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		apiKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, 1, apiKey.UsageStats.UniqueIPCount)
+		require.Contains(t, []string{"127.0.0.1", "::1"}, apiKey.UsageStats.MostRecentIP)
+	})
+
+	// Test per-key rate limiting
+	t.Run("TestApiKeyRateLimit", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			RateLimit:        &domain.RateLimit{RequestsPerMinute: 2},
+		})
+
+		for remaining := 1; remaining >= 0; remaining-- {
+			resp := postBearerValidation(t, apiKeyResponse.ApiKey)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, "2", resp.Header.Get(api.RateLimitLimitHeader))
+			require.Equal(t, strconv.Itoa(remaining), resp.Header.Get(api.RateLimitRemainingHeader))
+		}
+
+		resp := postBearerValidation(t, apiKeyResponse.ApiKey)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+		var validationResponse api.ApiKeyValidationResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+		require.Equal(t, api.ErrorCodeRateLimited, validationResponse.ErrorCode)
+	})
+
+	// Test that refused validations are throttled too, so they cannot grow the usage history without bound
+	t.Run("TestApiKeyRateLimitBeforeRejections", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			DeniedCIDRs:      []string{"127.0.0.0/8", "::1/128"},
+			RateLimit:        &domain.RateLimit{RequestsPerMinute: 2},
+		})
+
+		for i := 0; i < 2; i++ {
+			validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusForbidden, statusCode)
+			require.Equal(t, api.ErrorCodeIPNotAllowed, validationResponse.ErrorCode)
+		}
+		for i := 0; i < 3; i++ {
+			validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusTooManyRequests, statusCode)
+			require.Equal(t, api.ErrorCodeRateLimited, validationResponse.ErrorCode)
+		}
+
+		apiKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, uint64(2), apiKey.UsageStats.RejectedRequests)
+	})
+
+	t.Run("TestApiKeyQuota", func(t *testing.T) {
+		hardKey := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Quota:            &domain.Quota{Daily: 1},
+		})
+
+		resp := postBearerValidation(t, hardKey.ApiKey)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = postBearerValidation(t, hardKey.ApiKey)
+		var validationResponse api.ApiKeyValidationResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+		resp.Body.Close()
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.NotEmpty(t, resp.Header.Get("Retry-After"))
+		require.Equal(t, api.ErrorCodeQuotaExceeded, validationResponse.ErrorCode)
+
+		listed := findListedApiKey(t, hardKey.ApiId)
+		require.NotNil(t, listed.UsageStats.Quota)
+		require.NotNil(t, listed.UsageStats.Quota.Daily)
+		require.Equal(t, uint64(1), listed.UsageStats.Quota.Daily.Used)
+		require.Equal(t, uint64(0), listed.UsageStats.Quota.Daily.Remaining)
+
+		softKey := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Quota:            &domain.Quota{Monthly: 1, Enforcement: domain.QuotaEnforcementSoft},
+		})
+
+		resp = postBearerValidation(t, softKey.ApiKey)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get(api.QuotaExceededHeader))
+
+		resp = postBearerValidation(t, softKey.ApiKey)
+		validationResponse = api.ApiKeyValidationResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "true", resp.Header.Get(api.QuotaExceededHeader))
+		require.True(t, validationResponse.Valid)
+		require.True(t, validationResponse.QuotaExceeded)
+	})
+
+	t.Run("TestApiKeyScopes", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Scopes:           []string{"orders:read", "orders:write", "orders:read"},
+		})
+
+		validate := func(query string) (int, api.ApiKeyValidationResponse) {
+			req, err := http.NewRequest("POST", "http://localhost:8080/keys/validate"+query, nil)
+			require.NoError(t, err)
+			req.Header.Add("Authorization", "Bearer "+apiKeyResponse.ApiKey)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var validationResponse api.ApiKeyValidationResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+			return resp.StatusCode, validationResponse
+		}
+
+		statusCode, validationResponse := validate("")
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, []string{"orders:read", "orders:write"}, validationResponse.Scopes)
+
+		statusCode, _ = validate("?required_scope=orders:read,orders:write")
+		require.Equal(t, http.StatusOK, statusCode)
+
+		statusCode, validationResponse = validate("?required_scope=orders:read&required_scope=invoices:read")
+		require.Equal(t, http.StatusForbidden, statusCode)
+		require.Equal(t, api.ErrorCodeInsufficientScope, validationResponse.ErrorCode)
+		require.Contains(t, validationResponse.Message, "invoices:read")
+
+		listed := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, []string{"orders:read", "orders:write"}, listed.Scopes)
+		require.Equal(t, uint64(1), listed.UsageStats.RejectedRequests)
+	})
+
+	t.Run("TestApiKeyRotation", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Scopes:           []string{"orders:read"},
+		})
+
+		rotate := func(apiId string, body string) *http.Response {
+			resp, err := adminPost("http://localhost:8080/keys/"+apiId+"/rotate", "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			return resp
+		}
+
+		resp := rotate(apiKeyResponse.ApiId, `{"grace_period_seconds": 2}`)
+		var rotationResponse domain.ApiKeyRotationResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotationResponse))
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, apiKeyResponse.ApiId, rotationResponse.PredecessorId)
+		require.NotEqual(t, apiKeyResponse.ApiId, rotationResponse.ApiId)
+		require.NotEqual(t, apiKeyResponse.ApiKey, rotationResponse.ApiKey)
+		require.NotNil(t, rotationResponse.PredecessorExpirationDate)
+
+		// Both keys validate during the grace period
+		for _, apiKey := range []string{apiKeyResponse.ApiKey, rotationResponse.ApiKey} {
+			resp := postBearerValidation(t, apiKey)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		predecessor := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, rotationResponse.ApiId, predecessor.SuccessorId)
+		successor := findListedApiKey(t, rotationResponse.ApiId)
+		require.Equal(t, apiKeyResponse.ApiId, successor.PredecessorId)
+		require.Equal(t, []string{"orders:read"}, successor.Scopes)
+		require.Nil(t, successor.ExpirationDate)
+
+		resp = rotate(apiKeyResponse.ApiId, "")
+		resp.Body.Close()
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = rotate(uuid.NewString(), "")
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		time.Sleep(time.Until(*rotationResponse.PredecessorExpirationDate) + 100*time.Millisecond)
+
+		resp = postBearerValidation(t, apiKeyResponse.ApiKey)
+		resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = postBearerValidation(t, rotationResponse.ApiKey)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("TestAdminAuthentication", func(t *testing.T) {
+		callAs := func(token, method, url string, body string) int {
+			req, err := http.NewRequest(method, url, strings.NewReader(body))
+			require.NoError(t, err)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		createToken := func(roles ...string) domain.AdminTokenResponse {
+			request, err := json.Marshal(domain.AdminTokenRequest{Name: "e2e", Roles: roles})
+			require.NoError(t, err)
+			resp, err := adminPost("http://localhost:8080/admin/tokens", "application/json", bytes.NewReader(request))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var tokenResponse domain.AdminTokenResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokenResponse))
+			require.NotEmpty(t, tokenResponse.Token)
+			return tokenResponse
+		}
+
+		require.Equal(t, http.StatusUnauthorized, callAs("", "GET", "http://localhost:8080/keys", ""))
+		require.Equal(t, http.StatusUnauthorized, callAs("not-a-token", "GET", "http://localhost:8080/keys", ""))
+
+		viewer := createToken(domain.AdminRoleViewer)
+		require.Equal(t, http.StatusOK, callAs(viewer.Token, "GET", "http://localhost:8080/keys", ""))
+		require.Equal(t, http.StatusForbidden, callAs(viewer.Token, "POST", "http://localhost:8080/keys", `{"organization_name": "TestOrganization"}`))
+		require.Equal(t, http.StatusForbidden, callAs(viewer.Token, "GET", "http://localhost:8080/admin/tokens", ""))
+
+		issuer := createToken(domain.AdminRoleIssuer)
+		require.Equal(t, http.StatusOK, callAs(issuer.Token, "POST", "http://localhost:8080/keys", `{"organization_name": "TestOrganization"}`))
+		require.Equal(t, http.StatusForbidden, callAs(issuer.Token, "DELETE", "http://localhost:8080/keys/"+uuid.NewString(), ""))
+
+		revoker := createToken(domain.AdminRoleRevoker)
+		require.Equal(t, http.StatusNotFound, callAs(revoker.Token, "DELETE", "http://localhost:8080/keys/"+uuid.NewString(), ""))
+
+		// Validation stays callable without an admin token
+		apiKeyResponse := generateApiKey(t)
+		_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		resp, err := adminGet("http://localhost:8080/admin/tokens")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, string(body), viewer.TokenId)
+		require.NotContains(t, string(body), viewer.Token)
+
+		require.Equal(t, http.StatusOK, callAs(_adminRootToken, "DELETE", "http://localhost:8080/admin/tokens/"+viewer.TokenId, ""))
+		require.Equal(t, http.StatusUnauthorized, callAs(viewer.Token, "GET", "http://localhost:8080/keys", ""))
+	})
+
+	t.Run("TestOrganizations", func(t *testing.T) {
+		callOrganizations := func(method, url string, body string, result any) int {
+			req, err := newAdminRequest(method, url, strings.NewReader(body))
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			if result != nil && resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+			}
+			return resp.StatusCode
+		}
+
+		var organization domain.Organization
+		statusCode := callOrganizations("POST", "http://localhost:8080/orgs", `{"name": "Globex", "metadata": {"tier": "gold"}}`, &organization)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.NotEmpty(t, organization.OrganizationId)
+		require.Equal(t, domain.OrganizationStatusActive, organization.Status)
+		require.Equal(t, "gold", organization.Metadata["tier"])
+
+		statusCode = callOrganizations("POST", "http://localhost:8080/orgs", `{"name": "GLOBEX"}`, nil)
+		require.Equal(t, http.StatusConflict, statusCode)
+		statusCode = callOrganizations("GET", "http://localhost:8080/orgs/"+uuid.NewString(), "", nil)
+		require.Equal(t, http.StatusNotFound, statusCode)
+
+		byId := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId})
+		byName := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationName: " globex "})
+		require.Equal(t, organization.OrganizationId, findListedApiKey(t, byName.ApiId).OrganizationId)
+
+		validationResponse, statusCode := validateBearer(t, byId.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, organization.OrganizationId, validationResponse.OrganizationId)
+		require.Equal(t, "Globex", validationResponse.OrganizationName)
+
+		statusCode = callOrganizations("PATCH", "http://localhost:8080/orgs/"+organization.OrganizationId, `{"name": "Globex Corp"}`, &organization)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, "Globex Corp", findListedApiKey(t, byId.ApiId).OrganizationName)
+		require.Equal(t, "gold", organization.Metadata["tier"])
+
+		statusCode = callOrganizations("POST", "http://localhost:8080/orgs/"+organization.OrganizationId+"/suspend", "", &organization)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, domain.OrganizationStatusSuspended, organization.Status)
+
+		for _, apiKeyResponse := range []domain.ApiKeyGeneratorResponse{byId, byName} {
+			validationResponse, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusForbidden, statusCode)
+			require.Equal(t, api.ErrorCodeOrganizationSuspended, validationResponse.ErrorCode)
+		}
+
+		request, err := json.Marshal(domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId})
+		require.NoError(t, err)
+		resp, err := adminPost("http://localhost:8080/keys", "application/json", bytes.NewReader(request))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		statusCode = callOrganizations("POST", "http://localhost:8080/orgs/"+organization.OrganizationId+"/resume", "", nil)
+		require.Equal(t, http.StatusOK, statusCode)
+		_, statusCode = validateBearer(t, byId.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		var organizations domain.OrganizationListResponse
+		statusCode = callOrganizations("GET", "http://localhost:8080/orgs", "", &organizations)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, slices.ContainsFunc(organizations.Organizations, func(o *domain.Organization) bool {
+			return o.OrganizationId == organization.OrganizationId
+		}))
+	})
+
+	t.Run("TestOrganizationScopedKeys", func(t *testing.T) {
+		callAs := func(token, method, url string, body string, result any) int {
+			req, err := http.NewRequest(method, url, strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			if result != nil && resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+			}
+			return resp.StatusCode
+		}
+
+		var tenant, other domain.Organization
+		require.Equal(t, http.StatusOK, callAs(_adminRootToken, "POST", "http://localhost:8080/orgs", `{"name": "Initech"}`, &tenant))
+		require.Equal(t, http.StatusOK, callAs(_adminRootToken, "POST", "http://localhost:8080/orgs", `{"name": "Umbrella"}`, &other))
+		otherKey := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: other.OrganizationId})
+
+		// org_admin tokens must be bound to an existing organization
+		require.Equal(t, http.StatusBadRequest, callAs(_adminRootToken, "POST", "http://localhost:8080/admin/tokens", `{"name": "e2e", "roles": ["org_admin"]}`, nil))
+		require.Equal(t, http.StatusBadRequest, callAs(_adminRootToken, "POST", "http://localhost:8080/admin/tokens",
+			`{"name": "e2e", "roles": ["org_admin"], "organization_id": "`+uuid.NewString()+`"}`, nil))
+
+		var orgAdmin domain.AdminTokenResponse
+		require.Equal(t, http.StatusOK, callAs(_adminRootToken, "POST", "http://localhost:8080/admin/tokens",
+			`{"name": "e2e", "roles": ["org_admin"], "organization_id": "`+tenant.OrganizationId+`"}`, &orgAdmin))
+		require.Equal(t, tenant.OrganizationId, orgAdmin.OrganizationId)
+
+		tenantKeysUrl := "http://localhost:8080/orgs/" + tenant.OrganizationId + "/keys"
+		otherKeysUrl := "http://localhost:8080/orgs/" + other.OrganizationId + "/keys"
+
+		var tenantKey domain.ApiKeyGeneratorResponse
+		require.Equal(t, http.StatusOK, callAs(orgAdmin.Token, "POST", tenantKeysUrl, `{"name": "checkout"}`, &tenantKey))
+		require.Equal(t, tenant.OrganizationId, findListedApiKey(t, tenantKey.ApiId).OrganizationId)
+		require.Equal(t, http.StatusBadRequest, callAs(orgAdmin.Token, "POST", tenantKeysUrl, `{"organization_id": "`+other.OrganizationId+`"}`, nil))
+		// Scopes, quotas and rate limits are left to platform issuers
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "POST", tenantKeysUrl, `{"rate_limit": {"requests_per_second": 1000}}`, nil))
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "POST", tenantKeysUrl, `{"scopes": ["admin:billing"]}`, nil))
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "POST", tenantKeysUrl, `{"quota": {"daily": 1000000}}`, nil))
+
+		var apiKeyList domain.ApiKeyListResponse
+		require.Equal(t, http.StatusOK, callAs(orgAdmin.Token, "GET", tenantKeysUrl, "", &apiKeyList))
+		require.Equal(t, 1, apiKeyList.Total)
+		require.Equal(t, tenantKey.ApiId, apiKeyList.ApiKeys[0].ApiId)
+
+		// Other organizations and the platform endpoints stay out of reach
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "GET", otherKeysUrl, "", nil))
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "POST", otherKeysUrl, `{}`, nil))
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "GET", "http://localhost:8080/keys", "", nil))
+		require.Equal(t, http.StatusForbidden, callAs(orgAdmin.Token, "DELETE", "http://localhost:8080/keys/"+otherKey.ApiId, "", nil))
+		require.Equal(t, http.StatusNotFound, callAs(orgAdmin.Token, "DELETE", tenantKeysUrl+"/"+otherKey.ApiId, "", nil))
+		_, statusCode := validateBearer(t, otherKey.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		require.Equal(t, http.StatusOK, callAs(orgAdmin.Token, "DELETE", tenantKeysUrl+"/"+tenantKey.ApiId, "", nil))
+		_, statusCode = validateBearer(t, tenantKey.ApiKey)
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+
+		// Platform tokens keep working on organization scoped endpoints
+		require.Equal(t, http.StatusOK, callAs(_adminRootToken, "GET", otherKeysUrl, "", nil))
+	})
+
+	t.Run("TestOpaqueApiKeys", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			KeyAlgorithm:     domain.KeyAlgorithmOpaque,
+		})
+		formatted, err := keyformat.Parse(apiKeyResponse.ApiKey)
+		require.NoError(t, err)
+		lookupPrefix, secret, found := strings.Cut(formatted.Secret, ".")
+		require.True(t, found)
+		require.NotEmpty(t, secret)
+
+		validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, apiKeyResponse.ApiId, validationResponse.ApiId)
+
+		// A known prefix with the wrong secret is refused
+		forged, err := keyformat.New(formatted.Prefix, formatted.Environment, lookupPrefix+".not-the-secret")
+		require.NoError(t, err)
+		_, statusCode = validateBearer(t, forged.String())
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+
+		listedKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, domain.KeyAlgorithmOpaque, listedKey.KeyAlgorithm)
+		require.Equal(t, lookupPrefix, listedKey.LookupPrefix)
+
+		resp, err := adminPost("http://localhost:8080/keys/"+apiKeyResponse.ApiId+"/rotate", "application/json", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var rotationResponse domain.ApiKeyRotationResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotationResponse))
+		require.Contains(t, rotationResponse.ApiKey, ".")
+		_, statusCode = validateBearer(t, rotationResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+	})
+
+	t.Run("TestKeyAlgorithms", func(t *testing.T) {
+		for _, keyAlgorithm := range []string{domain.KeyAlgorithmSecp256k1, domain.KeyAlgorithmEd25519, domain.KeyAlgorithmP256} {
+			apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+				OrganizationName: "TestOrganization",
+				KeyAlgorithm:     keyAlgorithm,
+			})
+			validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusOK, statusCode, keyAlgorithm)
+			require.Equal(t, apiKeyResponse.ApiId, validationResponse.ApiId)
+			require.Equal(t, keyAlgorithm, findListedApiKey(t, apiKeyResponse.ApiId).KeyAlgorithm)
+		}
+
+		// Keys of an organization default to the organization's algorithm
+		req, err := newAdminRequest("POST", "http://localhost:8080/orgs", strings.NewReader(`{"name": "Hooli", "key_algorithm": "ed25519"}`))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var organization domain.Organization
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&organization))
+
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId})
+		require.Equal(t, domain.KeyAlgorithmEd25519, findListedApiKey(t, apiKeyResponse.ApiId).KeyAlgorithm)
+		_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		request, err := json.Marshal(domain.ApiKeyGeneratorRequest{OrganizationName: "TestOrganization", KeyAlgorithm: "rsa"})
+		require.NoError(t, err)
+		resp, err = adminPost("http://localhost:8080/keys", "application/json", bytes.NewReader(request))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("TestKeyFormat", func(t *testing.T) {
+		apiKeyResponse := generateApiKey(t)
+		formatted, err := keyformat.Parse(apiKeyResponse.ApiKey)
+		require.NoError(t, err)
+		require.Equal(t, "akm", formatted.Prefix)
+		require.Equal(t, "live", formatted.Environment)
+
+		// Scanners find keys in surrounding text
+		logLine := fmt.Sprintf("GET /orders Authorization: Bearer %s user=42", apiKeyResponse.ApiKey)
+		require.Equal(t, []string{apiKeyResponse.ApiKey}, keyformat.Find(logLine, "akm"))
+
+		// A typo is caught by the checksum
+		typo := []byte(apiKeyResponse.ApiKey)
+		typo[len("akm_live_")] = lo.Ternary(typo[len("akm_live_")] == '0', byte('1'), byte('0'))
+		require.Empty(t, keyformat.Find(string(typo), "akm"))
+		validationResponse, statusCode := validateBearer(t, string(typo))
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+		require.Equal(t, api.ErrorCodeMalformedApiKey, validationResponse.ErrorCode)
+
+		// Keys issued before the format existed are still accepted bare
+		_, statusCode = validateBearer(t, formatted.Secret)
+		require.Equal(t, http.StatusOK, statusCode)
+	})
+
+	t.Run("TestLeakReporting", func(t *testing.T) {
+		reportLeak := func(report domain.LeakReport) (domain.LeakReportResponse, int) {
+			request, err := json.Marshal(report)
+			require.NoError(t, err)
+			resp, err := adminPost("http://localhost:8080/keys/report-leak", "application/json", bytes.NewReader(request))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var reportResponse domain.LeakReportResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&reportResponse))
+			}
+			return reportResponse, resp.StatusCode
+		}
+
+		apiKeyResponse := generateApiKey(t)
+		report := domain.LeakReport{ApiKey: apiKeyResponse.ApiKey, Source: "github_secret_scanning", Url: "https://github.com/acme/app/blob/main/.env"}
+		reportResponse, statusCode := reportLeak(report)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, apiKeyResponse.ApiId, reportResponse.ApiId)
+		require.False(t, reportResponse.AlreadyExpired)
+		require.True(t, reportResponse.Notified)
+		require.Equal(t, domain.ApiKeyStatusRevoked, reportResponse.Status)
+
+		_, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+
+		// Reporting the same key again is recorded but changes nothing
+		reportResponse, statusCode = reportLeak(report)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, reportResponse.AlreadyExpired)
+
+		resp, err := adminGet("http://localhost:8080/audit/events?api_id=" + apiKeyResponse.ApiId)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var auditEvents domain.AuditEventListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&auditEvents))
+		require.Equal(t, 2, auditEvents.Total)
+		for _, event := range auditEvents.Events {
+			require.Equal(t, domain.AuditEventApiKeyLeaked, event.Type)
+			require.Equal(t, "github_secret_scanning", event.Details["source"])
+			require.Equal(t, report.Url, event.Details["url"])
+		}
+
+		unknownKey, err := keyformat.New("akm", "live", strings.Repeat("ab", 32))
+		require.NoError(t, err)
+		_, statusCode = reportLeak(domain.LeakReport{ApiKey: unknownKey.String(), Source: "pastebin"})
+		require.Equal(t, http.StatusNotFound, statusCode)
+		_, statusCode = reportLeak(domain.LeakReport{ApiKey: apiKeyResponse.ApiKey + "x", Source: "pastebin"})
+		require.Equal(t, http.StatusBadRequest, statusCode)
+		_, statusCode = reportLeak(domain.LeakReport{ApiKey: apiKeyResponse.ApiKey})
+		require.Equal(t, http.StatusBadRequest, statusCode)
+	})
+
+	t.Run("TestKeyEnvironments", func(t *testing.T) {
+		testKey := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationName: "TestOrganization", Environment: domain.EnvironmentTest})
+		require.True(t, strings.HasPrefix(testKey.ApiKey, "akm_test_"))
+		validationResponse, statusCode := validateBearer(t, testKey.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, domain.EnvironmentTest, validationResponse.Environment)
+
+		liveKey := generateApiKey(t)
+		validationResponse, statusCode = validateBearer(t, liveKey.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, domain.EnvironmentLive, validationResponse.Environment)
+
+		// Relabelling a key with another environment and a fresh checksum does not make it valid there
+		for _, apiKey := range []string{testKey.ApiKey, liveKey.ApiKey} {
+			formatted, err := keyformat.Parse(apiKey)
+			require.NoError(t, err)
+			otherEnvironment := lo.Ternary(formatted.Environment == domain.EnvironmentLive, domain.EnvironmentTest, domain.EnvironmentLive)
+			relabelled, err := keyformat.New(formatted.Prefix, otherEnvironment, formatted.Secret)
+			require.NoError(t, err)
+			_, statusCode = validateBearer(t, relabelled.String())
+			require.Equal(t, http.StatusUnauthorized, statusCode)
+		}
+
+		listApiKeys := func(environment string) (domain.ApiKeyListResponse, int) {
+			resp, err := adminGet("http://localhost:8080/keys?environment=" + environment)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var listResponse domain.ApiKeyListResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResponse))
+			}
+			return listResponse, resp.StatusCode
+		}
+		testKeys, statusCode := listApiKeys(domain.EnvironmentTest)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, []string{testKey.ApiId}, lo.Map(testKeys.ApiKeys, func(apiKey domain.ApiKeyWithStats, _ int) string { return apiKey.ApiId }))
+		require.Equal(t, domain.EnvironmentTest, testKeys.ApiKeys[0].Environment)
+		liveKeys, statusCode := listApiKeys(domain.EnvironmentLive)
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, lo.SomeBy(liveKeys.ApiKeys, func(apiKey domain.ApiKeyWithStats) bool { return apiKey.ApiId == liveKey.ApiId }))
+		require.False(t, lo.SomeBy(liveKeys.ApiKeys, func(apiKey domain.ApiKeyWithStats) bool { return apiKey.ApiId == testKey.ApiId }))
+		_, statusCode = listApiKeys("staging")
+		require.Equal(t, http.StatusBadRequest, statusCode)
+
+		request, err := json.Marshal(domain.ApiKeyGeneratorRequest{OrganizationName: "TestOrganization", Environment: "staging"})
+		require.NoError(t, err)
+		resp, err := adminPost("http://localhost:8080/keys", "application/json", bytes.NewReader(request))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("TestKeyExpiration", func(t *testing.T) {
+		postKey := func(body string) (domain.ApiKeyGeneratorResponse, int) {
+			resp, err := adminPost("http://localhost:8080/keys", "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var apiKeyResponse domain.ApiKeyGeneratorResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiKeyResponse))
+			}
+			return apiKeyResponse, resp.StatusCode
+		}
+
+		apiKeyResponse, statusCode := postKey(`{"organization_name": "TestOrganization", "ttl_seconds": 3600}`)
+		require.Equal(t, http.StatusOK, statusCode)
+		listed := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.NotNil(t, listed.ExpirationDate)
+		require.WithinDuration(t, time.Now().Add(time.Hour), *listed.ExpirationDate, time.Minute)
+		require.False(t, listed.IsExpired)
+		_, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+		apiKeyResponse, statusCode = postKey(fmt.Sprintf(`{"organization_name": "TestOrganization", "expires_at": %q}`, expiresAt.Format(time.RFC3339)))
+		require.Equal(t, http.StatusOK, statusCode)
+		require.True(t, expiresAt.Equal(*findListedApiKey(t, apiKeyResponse.ApiId).ExpirationDate))
+
+		past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		for _, body := range []string{
+			fmt.Sprintf(`{"organization_name": "TestOrganization", "expires_at": %q}`, past),
+			`{"organization_name": "TestOrganization", "ttl_seconds": 0}`,
+			fmt.Sprintf(`{"organization_name": "TestOrganization", "ttl_seconds": 60, "expires_at": %q}`, expiresAt.Format(time.RFC3339)),
+		} {
+			_, statusCode = postKey(body)
+			require.Equal(t, http.StatusBadRequest, statusCode, body)
+		}
+
+		// Keys of an organization with a maximum lifetime expire within it, including rotated ones
+		req, err := newAdminRequest("POST", "http://localhost:8080/orgs", strings.NewReader(`{"name": "Vandelay Industries", "max_key_lifetime_seconds": 600}`))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var organization domain.Organization
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&organization))
+		require.Equal(t, int64(600), organization.MaxKeyLifetimeSeconds)
+
+		apiKeyResponse = generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId})
+		listed = findListedApiKey(t, apiKeyResponse.ApiId)
+		require.NotNil(t, listed.ExpirationDate)
+		require.WithinDuration(t, time.Now().Add(10*time.Minute), *listed.ExpirationDate, time.Minute)
+		_, statusCode = postKey(fmt.Sprintf(`{"organization_id": %q, "ttl_seconds": 3600}`, organization.OrganizationId))
+		require.Equal(t, http.StatusBadRequest, statusCode)
+
+		resp, err = adminPost("http://localhost:8080/keys/"+apiKeyResponse.ApiId+"/rotate", "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var rotationResponse domain.ApiKeyRotationResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotationResponse))
+		successor := findListedApiKey(t, rotationResponse.ApiId)
+		require.NotNil(t, successor.ExpirationDate)
+		require.WithinDuration(t, time.Now().Add(10*time.Minute), *successor.ExpirationDate, time.Minute)
+
+		// Lifting the limit lets new keys live forever again
+		req, err = newAdminRequest("PATCH", "http://localhost:8080/orgs/"+organization.OrganizationId, strings.NewReader(`{"max_key_lifetime_seconds": 0}`))
+		require.NoError(t, err)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		apiKeyResponse = generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId})
+		require.Nil(t, findListedApiKey(t, apiKeyResponse.ApiId).ExpirationDate)
+	})
+
+	t.Run("TestApiKeyDetails", func(t *testing.T) {
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Name:             "checkout",
+			Labels:           map[string]string{"team": "payments"},
+		})
+		_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		keyURL := "http://localhost:8080/keys/" + apiKeyResponse.ApiId
+		callKey := func(method string, body string) (domain.ApiKeyWithStats, int) {
+			req, err := newAdminRequest(method, keyURL, strings.NewReader(body))
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var apiKey domain.ApiKeyWithStats
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiKey))
+			}
+			return apiKey, resp.StatusCode
+		}
+
+		apiKey, statusCode := callKey("GET", "")
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, apiKeyResponse.ApiId, apiKey.ApiId)
+		require.Equal(t, "checkout", apiKey.Name)
+		require.Equal(t, map[string]string{"team": "payments"}, apiKey.Labels)
+		require.Equal(t, uint64(1), apiKey.UsageStats.TotalRequests)
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		updated, statusCode := callKey("PATCH", fmt.Sprintf(
+			`{"version": %d, "description": "used by the checkout service", "scopes": ["orders:read"], "expires_at": %q}`,
+			apiKey.Version, expiresAt.Format(time.RFC3339)))
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, apiKey.Version+1, updated.Version)
+		require.Equal(t, "checkout", updated.Name)
+		require.Equal(t, "used by the checkout service", updated.Description)
+		require.Equal(t, []string{"orders:read"}, updated.Scopes)
+		require.True(t, expiresAt.Equal(*updated.ExpirationDate))
+
+		// An edit based on the old version loses against the one already applied
+		_, statusCode = callKey("PATCH", fmt.Sprintf(`{"version": %d, "name": "stale"}`, apiKey.Version))
+		require.Equal(t, http.StatusConflict, statusCode)
+		_, statusCode = callKey("PATCH", `{"name": "unversioned"}`)
+		require.Equal(t, http.StatusBadRequest, statusCode)
+		_, statusCode = callKey("PATCH", fmt.Sprintf(`{"version": %d, "expires_at": "2020-01-01T00:00:00Z"}`, updated.Version))
+		require.Equal(t, http.StatusBadRequest, statusCode)
+		_, statusCode = callKey("PATCH", fmt.Sprintf(`{"version": %d, "scopes": ["orders read"]}`, updated.Version))
+		require.Equal(t, http.StatusBadRequest, statusCode)
+		require.Equal(t, "checkout", findListedApiKey(t, apiKeyResponse.ApiId).Name)
+
+		keyURL = "http://localhost:8080/keys/" + uuid.NewString()
+		_, statusCode = callKey("GET", "")
+		require.Equal(t, http.StatusNotFound, statusCode)
+		_, statusCode = callKey("PATCH", `{"version": 1, "name": "missing"}`)
+		require.Equal(t, http.StatusNotFound, statusCode)
+	})
+
+	t.Run("TestApiKeyListingQueries", func(t *testing.T) {
+		label := "batch:" + uuid.NewString()
+		batch := map[string]string{"batch": strings.TrimPrefix(label, "batch:")}
+		var apiIds []string
+		for i := 0; i < 5; i++ {
+			apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationName: "TestOrganization", Labels: batch})
+			apiIds = append(apiIds, apiKeyResponse.ApiId)
+			for j := 0; j < i; j++ {
+				_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+				require.Equal(t, http.StatusOK, statusCode)
+			}
+		}
+		listed := func(apiKeys []domain.ApiKeyWithStats) []string {
+			return lo.Map(apiKeys, func(apiKey domain.ApiKeyWithStats, _ int) string { return apiKey.ApiId })
+		}
+
+		// Pages of two, newest first, cover every key exactly once
+		baseURL := "http://localhost:8080/keys?label=" + label
+		resp, err := adminGet(baseURL + "&limit=2")
+		require.NoError(t, err)
+		var firstPage domain.ApiKeyListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&firstPage))
+		resp.Body.Close()
+		require.Len(t, firstPage.ApiKeys, 2)
+		require.Equal(t, 5, firstPage.Total)
+		require.NotEmpty(t, firstPage.NextCursor)
+		require.Equal(t, lo.Reverse(slices.Clone(apiIds)), listed(listAllApiKeys(t, baseURL+"&limit=2")))
+		require.Equal(t, apiIds, listed(listAllApiKeys(t, baseURL+"&limit=2&order=asc")))
+
+		// Sorting by usage puts the most used key first
+		require.Equal(t, lo.Reverse(slices.Clone(apiIds)), listed(listAllApiKeys(t, baseURL+"&limit=3&sort=total_requests")))
+		require.Len(t, listAllApiKeys(t, baseURL+"&last_used_after="+time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)), 4)
+
+		// Revoked keys can be filtered out
+		req, err := newAdminRequest("DELETE", "http://localhost:8080/keys/"+apiIds[0], nil)
+		require.NoError(t, err)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, apiIds[1:], listed(listAllApiKeys(t, baseURL+"&status=active&order=asc")))
+		require.Equal(t, apiIds[:1], listed(listAllApiKeys(t, baseURL+"&status=revoked")))
+		require.Empty(t, listAllApiKeys(t, baseURL+"&expired=true"))
+
+		for _, query := range []string{"sort=name", "order=up", "limit=1000", "limit=-1", "expired=maybe", "status=deleted", "label=batch", "last_used_after=yesterday", "cursor=bogus"} {
+			resp, err := adminGet("http://localhost:8080/keys?" + query)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+
+		// A cursor only continues the order it was issued for
+		resp, err = adminGet(baseURL + "&limit=2&order=asc&cursor=" + firstPage.NextCursor)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("TestKeyLifecycle", func(t *testing.T) {
+		label := "lifecycle:" + uuid.NewString()
+		apiKeyResponse := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Labels:           map[string]string{"lifecycle": strings.TrimPrefix(label, "lifecycle:")},
+		})
+		apiKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, "root", apiKey.CreatedBy)
+		require.False(t, apiKey.CreatedAt.IsZero())
+		require.True(t, apiKey.UpdatedAt.Equal(apiKey.CreatedAt))
+		require.Nil(t, apiKey.RevokedAt)
+
+		deleteKey := func(reason string) int {
+			req, err := newAdminRequest("DELETE", "http://localhost:8080/keys/"+apiKeyResponse.ApiId+"?reason="+url.QueryEscape(reason), nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		require.Equal(t, http.StatusBadRequest, deleteKey(strings.Repeat("x", 501)))
+		require.Equal(t, http.StatusOK, deleteKey("employee offboarded"))
+
+		revoked := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, domain.ApiKeyStatusRevoked, revoked.Status)
+		require.False(t, revoked.IsExpired)
+		require.NotNil(t, revoked.RevokedAt)
+		require.Equal(t, "root", revoked.RevokedBy)
+		require.Equal(t, "employee offboarded", revoked.RevocationReason)
+		require.True(t, revoked.UpdatedAt.Equal(*revoked.RevokedAt))
+
+		// Revocation is final, deleting again keeps the original revocation
+		require.Equal(t, http.StatusConflict, deleteKey("duplicate"))
+		require.Equal(t, "employee offboarded", findListedApiKey(t, apiKeyResponse.ApiId).RevocationReason)
+
+		// A fresh key is not stale, and the stale report only lists active keys
+		fresh := generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
+			OrganizationName: "TestOrganization",
+			Labels:           map[string]string{"lifecycle": strings.TrimPrefix(label, "lifecycle:")},
+		})
+		require.Len(t, listAllApiKeys(t, "http://localhost:8080/keys/stale?days=1&label="+label), 0)
+		require.Len(t, listAllApiKeys(t, "http://localhost:8080/keys?status=active&label="+label), 1)
+		require.Equal(t, fresh.ApiId, listAllApiKeys(t, "http://localhost:8080/keys?status=active&label="+label)[0].ApiId)
+
+		for _, query := range []string{"days=0", "days=-3", "days=soon", "sort=name"} {
+			resp, err := adminGet("http://localhost:8080/keys/stale?" + query)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("TestKeySuspension", func(t *testing.T) {
+		apiKeyResponse := generateApiKey(t)
+		keyURL := "http://localhost:8080/keys/" + apiKeyResponse.ApiId
+		callKey := func(method string, path string) int {
+			req, err := newAdminRequest(method, keyURL+path, nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		require.Equal(t, http.StatusOK, callKey("POST", "/suspend"))
+		require.Equal(t, http.StatusConflict, callKey("POST", "/suspend"))
+		validationResponse, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusForbidden, statusCode)
+		require.Equal(t, api.ErrorCodeApiKeySuspended, validationResponse.ErrorCode)
+		require.Equal(t, domain.ApiKeyStatusSuspended, validationResponse.Status)
+		require.Equal(t, domain.ApiKeyStatusSuspended, findListedApiKey(t, apiKeyResponse.ApiId).Status)
+
+		// Suspended keys cannot be rotated, but resume where they left off
+		require.Equal(t, http.StatusBadRequest, callKey("POST", "/rotate"))
+		require.Equal(t, http.StatusOK, callKey("POST", "/resume"))
+		require.Equal(t, http.StatusConflict, callKey("POST", "/resume"))
+		_, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		// Revocation is final
+		require.Equal(t, http.StatusOK, callKey("POST", "/suspend"))
+		require.Equal(t, http.StatusOK, callKey("DELETE", ""))
+		require.Equal(t, http.StatusConflict, callKey("POST", "/resume"))
+		validationResponse, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+		require.Equal(t, api.ErrorCodeApiKeyRevoked, validationResponse.ErrorCode)
+		require.Equal(t, domain.ApiKeyStatusRevoked, validationResponse.Status)
+
+		keyURL = "http://localhost:8080/keys/" + uuid.NewString()
+		require.Equal(t, http.StatusNotFound, callKey("POST", "/suspend"))
+	})
+
+	t.Run("TestKeyPurge", func(t *testing.T) {
+		purge := func(url string) (domain.PurgeResponse, int) {
+			resp, err := adminPost(url, "application/json", nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var purgeResponse domain.PurgeResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&purgeResponse))
+			}
+			return purgeResponse, resp.StatusCode
+		}
+
+		apiKeyResponse := generateApiKey(t)
+		for i := 0; i < 2; i++ {
+			_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusOK, statusCode)
+		}
+		purgeResponse, statusCode := purge("http://localhost:8080/keys/" + apiKeyResponse.ApiId + "/purge")
+		require.Equal(t, http.StatusOK, statusCode)
+		require.Equal(t, []string{apiKeyResponse.ApiId}, purgeResponse.ApiIds)
+		require.Equal(t, 2, purgeResponse.UsageRecords)
+
+		// Nothing but the tombstone is left
+		resp, err := adminGet("http://localhost:8080/keys/" + apiKeyResponse.ApiId)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		_, statusCode = validateBearer(t, apiKeyResponse.ApiKey)
+		require.Equal(t, http.StatusUnauthorized, statusCode)
+		_, statusCode = purge("http://localhost:8080/keys/" + apiKeyResponse.ApiId + "/purge")
+		require.Equal(t, http.StatusNotFound, statusCode)
+
+		resp, err = adminGet("http://localhost:8080/audit/events?api_id=" + apiKeyResponse.ApiId)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var auditEvents domain.AuditEventListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&auditEvents))
+		require.Equal(t, 1, auditEvents.Total)
+		require.Equal(t, domain.AuditEventApiKeyPurged, auditEvents.Events[0].Type)
+		require.Equal(t, "root", auditEvents.Events[0].Details["purged_by"])
+		require.Equal(t, "2", auditEvents.Events[0].Details["usage_records"])
+
+		// Purging an organization removes all of its keys but keeps the organization
+		req, err := newAdminRequest("POST", "http://localhost:8080/orgs", strings.NewReader(`{"name": "Stark Industries"}`))
+		require.NoError(t, err)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var organization domain.Organization
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&organization))
+
+		var apiIds []string
+		for i := 0; i < 3; i++ {
+			apiIds = append(apiIds, generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{OrganizationId: organization.OrganizationId}).ApiId)
+		}
+		orgURL := "http://localhost:8080/orgs/" + organization.OrganizationId
+		purgeResponse, statusCode = purge(orgURL + "/keys/purge")
+		require.Equal(t, http.StatusOK, statusCode)
+		require.ElementsMatch(t, apiIds, purgeResponse.ApiIds)
+		require.Empty(t, listAllApiKeys(t, orgURL+"/keys"))
+
+		resp, err = adminGet(orgURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		_, statusCode = purge("http://localhost:8080/orgs/" + uuid.NewString() + "/keys/purge")
+		require.Equal(t, http.StatusNotFound, statusCode)
+	})
+
+	t.Run("TestKeyUsage", func(t *testing.T) {
+		getUsage := func(url string) (domain.ApiKeyUsageResponse, int) {
+			resp, err := adminGet(url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var usage domain.ApiKeyUsageResponse
+			if resp.StatusCode == http.StatusOK {
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+			}
+			return usage, resp.StatusCode
+		}
+
+		apiKeyResponse := generateApiKey(t)
+		for i := 0; i < 3; i++ {
+			_, statusCode := validateBearer(t, apiKeyResponse.ApiKey)
+			require.Equal(t, http.StatusOK, statusCode)
+		}
+
+		// The validations may straddle the turn of an hour or day
+		usageURL := "http://localhost:8080/keys/" + apiKeyResponse.ApiId + "/usage"
+		for _, granularity := range []string{"", domain.UsageGranularityHour, domain.UsageGranularityDay} {
+			usage, statusCode := getUsage(usageURL + "?granularity=" + granularity)
+			require.Equal(t, http.StatusOK, statusCode)
+			require.Equal(t, apiKeyResponse.ApiId, usage.ApiId)
+			require.NotEmpty(t, usage.Usage)
+			var requests uint64
+			for _, period := range usage.Usage {
+				requests += period.Requests
+				require.Equal(t, 1, period.UniqueIPCount)
+			}
+			require.Equal(t, uint64(3), requests)
+		}
+		usage, _ := getUsage(usageURL)
+		require.Equal(t, domain.UsageGranularityDay, usage.Granularity)
+
+		// Usage stats add up the daily rollups
+		listedApiKey := findListedApiKey(t, apiKeyResponse.ApiId)
+		require.Equal(t, uint64(3), listedApiKey.UsageStats.TotalRequests)
+		require.Equal(t, 1, listedApiKey.UsageStats.UniqueIPCount)
+		require.NotNil(t, listedApiKey.UsageStats.LastUsed)
+		require.Equal(t, usage.Usage[len(usage.Usage)-1].MostRecentIP, listedApiKey.UsageStats.MostRecentIP)
+
+		_, statusCode := getUsage(usageURL + "?granularity=week")
+		require.Equal(t, http.StatusBadRequest, statusCode)
+		_, statusCode = getUsage("http://localhost:8080/keys/" + uuid.NewString() + "/usage")
+		require.Equal(t, http.StatusNotFound, statusCode)
+	})
 }
 
 func generateApiKey(t *testing.T) domain.ApiKeyGeneratorResponse {
-	// Create request payload
-	request := domain.ApiKeyGeneratorRequest{
+	return generateApiKeyWithRequest(t, domain.ApiKeyGeneratorRequest{
 		OrganizationName: "TestOrganization",
-	}
+	})
+}
 
+func generateApiKeyWithRequest(t *testing.T, request domain.ApiKeyGeneratorRequest) domain.ApiKeyGeneratorResponse {
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(request)
 	if err != nil {
@@ -226,7 +1344,7 @@ func generateApiKey(t *testing.T) domain.ApiKeyGeneratorResponse {
 	}
 
 	// Make POST request to generate API key
-	resp, err := http.Post("http://localhost:8080/keys", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := adminPost("http://localhost:8080/keys", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		t.Fatalf("failed to make POST request: %v", err)
 	}
@@ -312,3 +1430,102 @@ func validateApiKey(t *testing.T, apiKeyResponse domain.ApiKeyGeneratorResponse)
 
 	t.Logf("Successfully validated API key with ID: %v", validationResponse["api_id"])
 }
+
+func validateSignedRequest(t *testing.T, apiKey string, signedRequest domain.SignedRequest, body []byte) (api.ApiKeyValidationResponse, int) {
+	signature, err := usecase.SignRequest(apiKey, signedRequest)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", "http://localhost:8080/keys/validate", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Add("Authorization", "Signature "+signature)
+	req.Header.Add(api.TimestampHeader, strconv.FormatInt(signedRequest.Timestamp, 10))
+	req.Header.Add(api.NonceHeader, signedRequest.Nonce)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var validationResponse api.ApiKeyValidationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+	return validationResponse, resp.StatusCode
+}
+
+func validateBearer(t *testing.T, apiKey string) (api.ApiKeyValidationResponse, int) {
+	resp := postBearerValidation(t, apiKey)
+	defer resp.Body.Close()
+
+	var validationResponse api.ApiKeyValidationResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&validationResponse))
+	return validationResponse, resp.StatusCode
+}
+
+func findListedApiKey(t *testing.T, apiId string) domain.ApiKeyWithStats {
+	for _, apiKey := range listAllApiKeys(t, "http://localhost:8080/keys?limit=200") {
+		if apiKey.ApiId == apiId {
+			return apiKey
+		}
+	}
+	t.Fatalf("API key %s not found in listing", apiId)
+	return domain.ApiKeyWithStats{}
+}
+
+// listAllApiKeys follows the cursors of a listing until its last page
+func listAllApiKeys(t *testing.T, url string) []domain.ApiKeyWithStats {
+	var apiKeys []domain.ApiKeyWithStats
+	cursor := ""
+	for {
+		pageURL := url
+		if cursor != "" {
+			pageURL += "&cursor=" + cursor
+		}
+		resp, err := adminGet(pageURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var listResponse domain.ApiKeyListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&listResponse))
+		resp.Body.Close()
+
+		apiKeys = append(apiKeys, listResponse.ApiKeys...)
+		if listResponse.NextCursor == "" {
+			return apiKeys
+		}
+		cursor = listResponse.NextCursor
+	}
+}
+
+func postBearerValidation(t *testing.T, apiKey string) *http.Response {
+	req, err := http.NewRequest("POST", "http://localhost:8080/keys/validate", nil)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// newAdminRequest is http.NewRequest for management endpoints, authenticated with the root admin token
+func newAdminRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+_adminRootToken)
+	return req, nil
+}
+
+func adminGet(url string) (*http.Response, error) {
+	req, err := newAdminRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func adminPost(url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := newAdminRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,378 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreRecovery(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir, SnapshotEveryRecords: 5}
+
+	// The first store is never closed, as if the process had crashed
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME"}))
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-2", Address: "0xdef", OrganizationName: "ACME"}))
+	for i := 0; i < 7; i++ {
+		require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}))
+	}
+	expiredAt := time.Now().Add(-time.Minute).UTC()
+	require.NoError(t, store.ExpireApiKey("key-2", &expiredAt))
+
+	// Leave a torn record at the end of the log
+	walFile, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = walFile.WriteString(`{"seq":99,"op":"store_api_key","data":{"api_id":"torn"`)
+	require.NoError(t, err)
+	require.NoError(t, walFile.Close())
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+
+	apiKey, err := recovered.GetApiKeyByAddress("0xabc")
+	require.NoError(t, err)
+	require.NotNil(t, apiKey)
+	require.Equal(t, "key-1", apiKey.ApiId)
+
+	expiredKey, exists, err := recovered.GetApiKey("key-2")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.NotNil(t, expiredKey.ExpirationDate)
+	require.True(t, expiredKey.ExpirationDate.Equal(expiredAt))
+
+	_, exists, err = recovered.GetApiKey("torn")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	usages, err := recovered.GetAllApiUsages()
+	require.NoError(t, err)
+	require.Len(t, usages["key-1"], 7)
+
+	// The torn tail was truncated, so new records append cleanly after it
+	usage := &domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.2", ValidatedAt: time.Now()}
+	require.NoError(t, recovered.StoreApiUsage(usage))
+	require.Equal(t, uint64(8), usage.CumulativeRequest)
+}
+
+func TestFileStoreLegacyApiKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	// Keys written before key formats existed kept the address of the key in private_key
+	data := []byte(`{"api_id":"legacy","private_key":"0xabc","organization_name":"ACME","expiration_date":null}`)
+	line, err := json.Marshal(map[string]any{"seq": 1, "op": "store_api_key", "data": json.RawMessage(data), "crc": crc32.ChecksumIEEE(data)})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wal.log"), append(line, '\n'), 0o600))
+
+	store, err := infra.NewFileStore(context.Background(), infra.FileStoreOptions{Directory: dir})
+	require.NoError(t, err)
+	defer store.Close()
+
+	apiKey, err := store.GetApiKeyByAddress("0xabc")
+	require.NoError(t, err)
+	require.NotNil(t, apiKey)
+	require.Equal(t, "legacy", apiKey.ApiId)
+	require.Equal(t, domain.KeyAlgorithmSecp256k1, apiKey.KeyAlgorithm)
+}
+
+func TestFileStoreApiKeyVersions(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME"}))
+	require.NoError(t, store.UpdateApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME", Name: "checkout"}, 1))
+
+	// An update based on an outdated version is refused and not logged
+	err = store.UpdateApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME", Name: "stale"}, 1)
+	require.ErrorIs(t, err, domain.ErrVersionConflict)
+	require.NoError(t, store.ResetApiKeyIPBinding("key-1", time.Now()))
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	apiKey, exists, err := recovered.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, "checkout", apiKey.Name)
+	require.Equal(t, int64(3), apiKey.Version)
+}
+
+func TestFileStoreApiKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", OrganizationName: "ACME"}))
+	expirationDate := time.Now().Add(time.Hour)
+	require.NoError(t, store.RotateApiKey("key-1", &domain.ApiKey{ApiId: "key-2", OrganizationName: "ACME"}, &expirationDate))
+
+	// A second rotation of the same key and a rotation of a missing key are refused and not logged
+	err = store.RotateApiKey("key-1", &domain.ApiKey{ApiId: "key-3", OrganizationName: "ACME"}, &expirationDate)
+	require.ErrorIs(t, err, domain.ErrApiKeyRotated)
+	err = store.RotateApiKey("missing", &domain.ApiKey{ApiId: "key-4", OrganizationName: "ACME"}, &expirationDate)
+	require.ErrorIs(t, err, domain.ErrApiKeyNotFound)
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	apiKey, exists, err := recovered.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, "key-2", apiKey.SuccessorId)
+	for _, apiId := range []string{"key-3", "key-4"} {
+		_, exists, err = recovered.GetApiKey(apiId)
+		require.NoError(t, err)
+		require.False(t, exists)
+	}
+}
+
+func TestFileStoreApiKeyQueries(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	createdAt := time.Now().Add(-time.Hour)
+	for i, apiId := range []string{"key-1", "key-2", "key-3"} {
+		require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: apiId, OrganizationName: "ACME", CreatedAt: createdAt.Add(time.Duration(i) * time.Minute)}))
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "key-2", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}))
+	}
+	require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}))
+	require.NoError(t, store.Close())
+
+	// Usage summaries are rebuilt from the snapshot
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	query := domain.ApiKeyQuery{SortBy: domain.ApiKeySortTotalRequests, Order: domain.SortOrderDescending, Limit: 2}
+	page, err := recovered.QueryApiKeys(query)
+	require.NoError(t, err)
+	require.Equal(t, 3, page.Total)
+	require.Equal(t, []string{"key-2", "key-1"}, []string{page.ApiKeys[0].ApiId, page.ApiKeys[1].ApiId})
+	require.NotEmpty(t, page.NextCursor)
+
+	query.Cursor = page.NextCursor
+	page, err = recovered.QueryApiKeys(query)
+	require.NoError(t, err)
+	require.Len(t, page.ApiKeys, 1)
+	require.Equal(t, "key-3", page.ApiKeys[0].ApiId)
+	require.Empty(t, page.NextCursor)
+}
+
+func TestFileStoreApiKeyRevocation(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "unused", OrganizationName: "ACME", CreatedAt: now.AddDate(0, 0, -120)}))
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "used", OrganizationName: "ACME", CreatedAt: now.AddDate(0, 0, -120)}))
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "new", OrganizationName: "ACME", CreatedAt: now.AddDate(0, 0, -1)}))
+	require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "used", IpAddress: "10.0.0.1", ValidatedAt: now}))
+
+	revocation := domain.Revocation{RevokedAt: now, RevokedBy: "token-1", Reason: "offboarded"}
+	require.NoError(t, store.RevokeApiKey("used", revocation))
+	err = store.RevokeApiKey("used", domain.Revocation{RevokedAt: now.Add(time.Minute), RevokedBy: "token-2"})
+	require.ErrorIs(t, err, domain.ErrInvalidStatusTransition)
+	require.NoError(t, store.Close())
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	apiKey, exists, err := recovered.GetApiKey("used")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, "token-1", apiKey.RevokedBy)
+	require.Equal(t, "offboarded", apiKey.RevocationReason)
+	require.Equal(t, domain.ApiKeyStatusRevoked, apiKey.StatusAt(now))
+	require.Nil(t, apiKey.ExpirationDate)
+
+	// Only the old key without recent usage is stale
+	unusedSince := now.AddDate(0, 0, -90)
+	page, err := recovered.QueryApiKeys(domain.ApiKeyQuery{Filter: domain.ApiKeyFilter{UnusedSince: &unusedSince}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.ApiKeys, 1)
+	require.Equal(t, "unused", page.ApiKeys[0].ApiId)
+}
+
+func TestFileStoreApiKeyStatus(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", OrganizationName: "ACME", Status: domain.ApiKeyStatusActive}))
+	require.NoError(t, store.SetApiKeyStatus("key-1", domain.ApiKeyStatusSuspended, now))
+
+	// A refused transition is not logged
+	err = store.SetApiKeyStatus("key-1", domain.ApiKeyStatusSuspended, now)
+	require.ErrorIs(t, err, domain.ErrInvalidStatusTransition)
+	require.NoError(t, store.Close())
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	apiKey, exists, err := recovered.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, domain.ApiKeyStatusSuspended, apiKey.StatusAt(now))
+	require.Equal(t, int64(2), apiKey.Version)
+
+	// Expiry outranks a suspension, and expired keys cannot be resumed
+	expired := now.Add(-time.Minute)
+	require.NoError(t, recovered.ExpireApiKey("key-1", &expired))
+	apiKey, _, err = recovered.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.Equal(t, domain.ApiKeyStatusExpired, apiKey.StatusAt(now))
+	require.ErrorIs(t, recovered.SetApiKeyStatus("key-1", domain.ApiKeyStatusActive, now), domain.ErrInvalidStatusTransition)
+}
+
+func TestFileStoreApiKeyPurge(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME"}))
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-2", Address: "0xdef", OrganizationName: "ACME"}))
+	require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "key-1", IpAddress: "203.0.113.7", ValidatedAt: time.Now()}))
+	require.NoError(t, store.StoreApiUsage(&domain.ApiUsage{ApiId: "key-2", IpAddress: "198.51.100.9", ValidatedAt: time.Now()}))
+	require.NoError(t, store.PurgeApiKeys([]string{"key-1", "unknown"}))
+	require.NoError(t, store.Close())
+
+	// The purged IP address is gone from every file of the store
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "203.0.113.7", file.Name())
+	}
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	_, exists, err := recovered.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.False(t, exists)
+	apiKey, err := recovered.GetApiKeyByAddress("0xabc")
+	require.NoError(t, err)
+	require.Nil(t, apiKey)
+	usages, err := recovered.GetApiUsages("key-1")
+	require.NoError(t, err)
+	require.Empty(t, usages)
+	usages, err = recovered.GetApiUsages("key-2")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+}
+
+func TestFileStoreUsageRollups(t *testing.T) {
+	dir := t.TempDir()
+	opts := infra.FileStoreOptions{Directory: dir, SnapshotEveryRecords: 3}
+
+	store, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME"}))
+	day := domain.UsagePeriodStart(domain.UsageGranularityDay, time.Now().AddDate(0, 0, -2))
+	for _, usage := range []*domain.ApiUsage{
+		{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: day.Add(70 * time.Minute)},
+		{ApiId: "key-1", IpAddress: "10.0.0.2", ValidatedAt: day.Add(80 * time.Minute)},
+		{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: day.Add(125 * time.Minute), RejectionReason: "rate limited"},
+		{ApiId: "key-1", IpAddress: "10.0.0.3", ValidatedAt: day.Add(26 * time.Hour)},
+	} {
+		require.NoError(t, store.StoreApiUsage(usage))
+	}
+	require.NoError(t, store.PruneApiUsages(day.Add(25*time.Hour), day.Add(2*time.Hour)))
+	require.NoError(t, store.Close())
+
+	recovered, err := infra.NewFileStore(context.Background(), opts)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	usages, err := recovered.GetApiUsages("key-1")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+
+	// The hour ending when hourly usage is pruned from is gone, the hours after it are kept
+	hours, err := recovered.GetApiUsageRollups("key-1", domain.UsageGranularityHour)
+	require.NoError(t, err)
+	require.Len(t, hours, 2)
+	require.True(t, hours[0].PeriodStart.Equal(day.Add(2*time.Hour)))
+	require.Equal(t, uint64(1), hours[0].RejectedRequests)
+
+	days, err := recovered.GetApiUsageRollups("key-1", domain.UsageGranularityDay)
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	require.True(t, days[0].PeriodStart.Equal(day))
+	require.Equal(t, uint64(2), days[0].Requests)
+	require.Equal(t, uint64(1), days[0].RejectedRequests)
+	require.Equal(t, uint64(2), days[0].UniqueIPs.Count())
+	require.Equal(t, "10.0.0.2", days[0].MostRecentIP)
+	require.Equal(t, uint64(1), days[1].Requests)
+
+	// Counts and the cumulative count carry on from the rollups once the records are pruned
+	count, err := recovered.CountApiUsages("key-1", day)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), count)
+	usage := &domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}
+	require.NoError(t, recovered.StoreApiUsage(usage))
+	require.Equal(t, uint64(4), usage.CumulativeRequest)
+}
+
+func TestFileStoreLegacyUsage(t *testing.T) {
+	dir := t.TempDir()
+
+	// Snapshots written before usage was rolled up only have the usage records
+	validatedAt := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	snapshot, err := json.Marshal(map[string]any{
+		"seq":      1,
+		"api_keys": []*domain.ApiKey{{ApiId: "key-1", Address: "0xabc", OrganizationName: "ACME"}},
+		"api_usages": map[string][]*domain.ApiUsage{"key-1": {
+			{ApiId: "key-1", IpAddress: "10.0.0.1", CumulativeRequest: 1, ValidatedAt: validatedAt},
+			{ApiId: "key-1", IpAddress: "10.0.0.2", CumulativeRequest: 2, ValidatedAt: validatedAt.Add(time.Hour)},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot.json"), snapshot, 0o600))
+
+	store, err := infra.NewFileStore(context.Background(), infra.FileStoreOptions{Directory: dir})
+	require.NoError(t, err)
+	defer store.Close()
+
+	hours, err := store.GetApiUsageRollups("key-1", domain.UsageGranularityHour)
+	require.NoError(t, err)
+	require.Len(t, hours, 2)
+	days, err := store.GetApiUsageRollups("key-1", domain.UsageGranularityDay)
+	require.NoError(t, err)
+	require.Len(t, days, 1)
+	require.Equal(t, uint64(2), days[0].Requests)
+	require.Equal(t, uint64(2), days[0].UniqueIPs.Count())
+
+	usage := &domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}
+	require.NoError(t, store.StoreApiUsage(usage))
+	require.Equal(t, uint64(3), usage.CumulativeRequest)
+}
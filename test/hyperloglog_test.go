@@ -0,0 +1,60 @@
+//go:build e2e
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/pkg/hyperloglog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLogEncoding(t *testing.T) {
+	var sparse, dense hyperloglog.Sketch
+	for i := 0; i < 10; i++ {
+		sparse.Add(fmt.Sprintf("10.0.0.%d", i))
+	}
+	for i := 0; i < 5000; i++ {
+		dense.Add(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	for _, sketch := range []hyperloglog.Sketch{sparse, dense} {
+		data, err := sketch.MarshalBinary()
+		require.NoError(t, err)
+		var decoded hyperloglog.Sketch
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.Equal(t, sketch.Count(), decoded.Count())
+	}
+
+	// Precision 12, sparse form, then big endian register index and rank
+	denseWith := func(rank byte) []byte {
+		data := make([]byte, 2+1<<12)
+		data[0], data[1] = 12, 1
+		data[2+7] = rank
+		return data
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{name: "sparse highest rank", data: []byte{12, 0, 0x00, 0x07, 53}, valid: true},
+		{name: "sparse rank 0", data: []byte{12, 0, 0x00, 0x07, 0}},
+		{name: "sparse rank above the highest", data: []byte{12, 0, 0x00, 0x07, 54}},
+		{name: "sparse register out of range", data: []byte{12, 0, 0x10, 0x00, 1}},
+		{name: "dense highest rank", data: denseWith(53), valid: true},
+		{name: "dense rank above the highest", data: denseWith(54)},
+		{name: "other precision", data: []byte{14, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sketch hyperloglog.Sketch
+			err := sketch.UnmarshalBinary(tt.data)
+			if tt.valid {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, hyperloglog.ErrInvalidEncoding)
+		})
+	}
+}
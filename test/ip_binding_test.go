@@ -0,0 +1,47 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefusedValidationsDoNotBindIP(t *testing.T) {
+	store := infra.NewDataStore()
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{
+		ApiId:        "key-1",
+		Address:      crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		KeyAlgorithm: domain.KeyAlgorithmSecp256k1,
+		Status:       domain.ApiKeyStatusActive,
+		Scopes:       []string{"read:orders"},
+	}))
+	apiKey := hex.EncodeToString(crypto.FromECDSA(privateKey))
+
+	validation := usecase.NewApiKeyValidation(store, usecase.ValidationPolicy{},
+		usecase.NewKeyAlgorithms(usecase.SecretPolicy{}, usecase.KeyFormatPolicy{Prefix: "akm"}), usecase.NewRateLimiter())
+	validate := func(ipAddress string, requiredScopes []string) error {
+		_, err := validation.ValidateApiKey(context.Background(), apiKey, ipAddress, requiredScopes)
+		return err
+	}
+
+	// A validation refused for its scopes leaves the key unbound
+	require.ErrorIs(t, validate("10.0.0.1", []string{"write:orders"}), domain.ErrInsufficientScope)
+	stored, _, err := store.GetApiKey("key-1")
+	require.NoError(t, err)
+	require.Empty(t, stored.BoundIP)
+
+	// The first accepted validation binds it
+	require.NoError(t, validate("10.0.0.2", []string{"read:orders"}))
+	require.ErrorIs(t, validate("10.0.0.1", nil), domain.ErrIPNotAllowed)
+	require.NoError(t, validate("10.0.0.2", nil))
+}
@@ -0,0 +1,119 @@
+//go:build e2e
+
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/pkg/keyformat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFormatNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		environment string
+		secret      string
+		wantErr     error
+		wantAnyErr  bool
+	}{
+		{name: "valid", prefix: "akm", environment: "live", secret: "3fa1c2d4e5b6.kQ7vZ-x_y"},
+		{name: "upper case prefix", prefix: "AKM", environment: "live", secret: "abc", wantAnyErr: true},
+		{name: "empty environment", prefix: "akm", environment: "", secret: "abc", wantAnyErr: true},
+		{name: "environment with separator", prefix: "akm", environment: "live_eu", secret: "abc", wantAnyErr: true},
+		{name: "secret with space", prefix: "akm", environment: "live", secret: "not a secret", wantErr: keyformat.ErrMalformed},
+		{name: "empty secret", prefix: "akm", environment: "live", secret: "", wantErr: keyformat.ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := keyformat.New(tt.prefix, tt.environment, tt.secret)
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.wantAnyErr:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, keyformat.Key{Prefix: tt.prefix, Environment: tt.environment, Secret: tt.secret}, key)
+			}
+		})
+	}
+}
+
+func TestKeyFormatParse(t *testing.T) {
+	valid := keyformat.Key{Prefix: "akm", Environment: "live", Secret: "3fa1c2d4e5b6.kQ7vZ-x_y"}
+	encoded := valid.String()
+	badChecksum := encoded[:len(encoded)-1] + string(lastCharOtherThan(encoded))
+
+	tests := []struct {
+		name    string
+		key     string
+		want    keyformat.Key
+		wantErr error
+	}{
+		{name: "valid", key: encoded, want: valid},
+		{name: "bad checksum", key: badChecksum, wantErr: keyformat.ErrChecksumMismatch},
+		{name: "typo in secret", key: strings.Replace(encoded, "kQ7", "kQ8", 1), wantErr: keyformat.ErrChecksumMismatch},
+		// String does not validate, so these carry a correct checksum over a label the format forbids
+		{name: "upper case environment", key: keyformat.Key{Prefix: "akm", Environment: "LIVE", Secret: "abc"}.String(), wantErr: keyformat.ErrMalformed},
+		{name: "empty prefix", key: keyformat.Key{Prefix: "", Environment: "live", Secret: "abc"}.String(), wantErr: keyformat.ErrMalformed},
+		{name: "missing checksum", key: "akm_live_abc", wantErr: keyformat.ErrMalformed},
+		{name: "short checksum", key: "akm_live_abc_12345", wantErr: keyformat.ErrMalformed},
+		{name: "surrounding text", key: "Bearer " + encoded, wantErr: keyformat.ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := keyformat.Parse(tt.key)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, key)
+		})
+	}
+}
+
+func TestKeyFormatFind(t *testing.T) {
+	first := keyformat.Key{Prefix: "akm", Environment: "live", Secret: "3fa1c2d4e5b6.kQ7vZ"}.String()
+	second := keyformat.Key{Prefix: "akm", Environment: "test", Secret: "9c8b7a6f5e4d.Zp2Lm"}.String()
+	otherPrefix := keyformat.Key{Prefix: "xyz", Environment: "live", Secret: "3fa1c2d4e5b6.kQ7vZ"}.String()
+	badChecksum := first[:len(first)-1] + string(lastCharOtherThan(first))
+	badLabel := keyformat.Key{Prefix: "akm", Environment: "LIVE", Secret: "3fa1c2d4e5b6.kQ7vZ"}.String()
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "key alone", text: first, want: []string{first}},
+		{name: "embedded in a log line", text: "GET /orders Authorization: Bearer " + first + " user=42", want: []string{first}},
+		{name: "embedded in JSON", text: `{"api_key":"` + first + `"}`, want: []string{first}},
+		{name: "several keys in order", text: second + "," + first, want: []string{second, first}},
+		{name: "keys joined by a dash", text: first + "-" + second, want: []string{first, second}},
+		{name: "keys joined by a dot", text: first + "." + second, want: []string{first, second}},
+		{name: "keys joined by an underscore", text: first + "_" + second, want: []string{first, second}},
+		{name: "bad checksum joined to a key", text: badChecksum + "-" + second, want: []string{second}},
+		{name: "key joined to a bad checksum", text: first + "-" + badChecksum, want: []string{first}},
+		{name: "bad checksum", text: "key=" + badChecksum, want: nil},
+		{name: "bad label", text: "key=" + badLabel, want: nil},
+		{name: "other prefix", text: "key=" + otherPrefix, want: nil},
+		{name: "glued to a word", text: "x" + first, want: nil},
+		{name: "no key", text: "nothing to see here", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, keyformat.Find(tt.text, "akm"))
+		})
+	}
+}
+
+// lastCharOtherThan returns a base62 character different from the last one of s
+func lastCharOtherThan(s string) byte {
+	if s[len(s)-1] == '0' {
+		return '1'
+	}
+	return '0'
+}
@@ -0,0 +1,47 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan domain.Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification domain.Notification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- notification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := infra.NewWebhookNotifier(server.URL, time.Second)
+	notification := domain.Notification{
+		Type:             domain.NotificationApiKeyLeaked,
+		OrganizationName: "ACME",
+		ApiId:            "key-1",
+		Message:          "API key key-1 was reported as leaked",
+		OccurredAt:       time.Now().UTC(),
+	}
+	require.NoError(t, notifier.Notify(context.Background(), notification))
+	require.Equal(t, "key-1", (<-received).ApiId)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	require.Error(t, infra.NewWebhookNotifier(failing.URL, time.Second).Notify(context.Background(), notification))
+}
@@ -0,0 +1,78 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationLimitsFollowTheOrganization(t *testing.T) {
+	store := infra.NewDataStore()
+	organizations := usecase.NewOrganizations(store)
+	validation := usecase.NewApiKeyValidation(store, usecase.ValidationPolicy{AllowMultipleIPs: true},
+		usecase.NewKeyAlgorithms(usecase.SecretPolicy{}, usecase.KeyFormatPolicy{Prefix: "akm"}), usecase.NewRateLimiter())
+	ctx := context.Background()
+
+	// storeKey stores a key of organization and returns it in the clear
+	storeKey := func(apiId string, organization *domain.Organization) string {
+		privateKey, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		require.NoError(t, store.StoreApiKey(&domain.ApiKey{
+			ApiId:            apiId,
+			Address:          crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+			KeyAlgorithm:     domain.KeyAlgorithmSecp256k1,
+			Status:           domain.ApiKeyStatusActive,
+			OrganizationId:   organization.OrganizationId,
+			OrganizationName: organization.Name,
+		}))
+		return hex.EncodeToString(crypto.FromECDSA(privateKey))
+	}
+	validate := func(apiKey string) error {
+		_, err := validation.ValidateApiKey(ctx, apiKey, "10.0.0.1", nil)
+		return err
+	}
+
+	limited, err := organizations.CreateOrganization(ctx, domain.OrganizationRequest{Name: "Limited"})
+	require.NoError(t, err)
+	metered, err := organizations.CreateOrganization(ctx, domain.OrganizationRequest{Name: "Metered"})
+	require.NoError(t, err)
+	limitedKey := storeKey("key-1", limited)
+	meteredKey := storeKey("key-2", metered)
+
+	_, err = organizations.UpdateOrganizationLimits(ctx, limited.OrganizationId, domain.OrganizationLimits{RateLimit: &domain.RateLimit{RequestsPerMinute: -1}})
+	require.ErrorIs(t, err, domain.ErrInvalidRequest)
+	_, err = organizations.UpdateOrganizationLimits(ctx, limited.OrganizationId, domain.OrganizationLimits{RateLimit: &domain.RateLimit{RequestsPerMinute: 2}})
+	require.NoError(t, err)
+	_, err = organizations.UpdateOrganizationLimits(ctx, metered.OrganizationId, domain.OrganizationLimits{Quota: &domain.Quota{Daily: 2}})
+	require.NoError(t, err)
+
+	// Renaming keeps the limits, and taking over a name gains none of its previous owner's limits
+	for _, organization := range []*domain.Organization{limited, metered} {
+		_, err = organizations.UpdateOrganization(ctx, organization.OrganizationId, domain.OrganizationRequest{Name: organization.Name + " Inc"})
+		require.NoError(t, err)
+	}
+	renamed, err := organizations.UpdateOrganization(ctx, limited.OrganizationId, domain.OrganizationRequest{Name: "Metered"})
+	require.NoError(t, err)
+	require.Equal(t, &domain.RateLimit{RequestsPerMinute: 2}, renamed.RateLimit)
+	require.Nil(t, renamed.Quota)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, validate(limitedKey))
+		require.NoError(t, validate(meteredKey))
+	}
+	require.ErrorIs(t, validate(limitedKey), domain.ErrRateLimited)
+	require.ErrorIs(t, validate(meteredKey), domain.ErrQuotaExceeded)
+
+	// Removing the quota lets the organization's keys validate again
+	_, err = organizations.UpdateOrganizationLimits(ctx, metered.OrganizationId, domain.OrganizationLimits{})
+	require.NoError(t, err)
+	require.NoError(t, validate(meteredKey))
+}
@@ -0,0 +1,65 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeQuotaIsAtomic(t *testing.T) {
+	fileStore, err := infra.NewFileStore(context.Background(), infra.FileStoreOptions{Directory: t.TempDir()})
+	require.NoError(t, err)
+	defer fileStore.Close()
+
+	stores := map[string]usecase.Repository{
+		"data store": infra.NewDataStore(),
+		"file store": fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			const quota = 5
+			since := time.Now().Add(-time.Minute)
+			check := func(counter domain.UsageCounter) error {
+				count, err := counter.CountApiUsages("key-1", since)
+				if err != nil {
+					return err
+				}
+				if count >= quota {
+					return domain.ErrQuotaExceeded
+				}
+				return nil
+			}
+
+			var accepted atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					usage := &domain.ApiUsage{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: time.Now()}
+					err := store.ConsumeQuota(usage, check)
+					if err == nil {
+						accepted.Add(1)
+						return
+					}
+					require.ErrorIs(t, err, domain.ErrQuotaExceeded)
+				}()
+			}
+			wg.Wait()
+
+			require.EqualValues(t, quota, accepted.Load())
+			count, err := store.CountApiUsages("key-1", since)
+			require.NoError(t, err)
+			require.EqualValues(t, quota, count)
+		})
+	}
+}
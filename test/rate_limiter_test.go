@@ -0,0 +1,36 @@
+//go:build e2e
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterDropsOnlyRefilledBuckets(t *testing.T) {
+	limiter := usecase.NewRateLimiter()
+	limit := domain.RateLimit{RequestsPerSecond: 0.01, Burst: 2}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		allowed, _ := limiter.Allow("key-1", limit, now)
+		require.True(t, allowed)
+	}
+	allowed, _ := limiter.Allow("key-1", limit, now)
+	require.False(t, allowed)
+
+	// After more than a sweep interval the bucket is still refilling, so the sweep keeps it and its debt
+	later := now.Add(75 * time.Second)
+	allowed, _ = limiter.Allow("key-1", limit, later)
+	require.False(t, allowed)
+
+	// A purged key starts over should its ID ever come back
+	limiter.Forget([]string{"key-1"})
+	allowed, status := limiter.Allow("key-1", limit, later)
+	require.True(t, allowed)
+	require.Equal(t, 1, status.Remaining)
+}
@@ -0,0 +1,67 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPurgesRevokedApiKeys(t *testing.T) {
+	store := infra.NewDataStore()
+	now := time.Now()
+	for apiId, revokedAt := range map[string]time.Time{"old": now.AddDate(0, 0, -31), "recent": now.AddDate(0, 0, -1)} {
+		require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: apiId, Status: domain.ApiKeyStatusActive}))
+		require.NoError(t, store.RevokeApiKey(apiId, domain.Revocation{RevokedAt: revokedAt}))
+	}
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "active", Status: domain.ApiKeyStatusActive}))
+
+	purge := usecase.NewApiKeyPurge(store, usecase.RetentionPolicy{PurgeRevokedAfter: 30 * 24 * time.Hour}, usecase.NewRateLimiter())
+	purged, err := purge.PurgeRevokedApiKeys(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"old"}, purged.ApiIds)
+
+	apiKeys, err := store.GetAllApiKeys()
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+	events, err := store.GetAllAuditEvents()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "retention", events[0].Details["purged_by"])
+}
+
+func TestRetentionPrunesApiUsages(t *testing.T) {
+	store := infra.NewDataStore()
+	now := time.Now()
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{ApiId: "key-1", Status: domain.ApiKeyStatusActive}))
+	for _, usage := range []*domain.ApiUsage{
+		{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: now.AddDate(0, 0, -10)},
+		{ApiId: "key-1", IpAddress: "10.0.0.2", ValidatedAt: now.AddDate(0, 0, -3)},
+		{ApiId: "key-1", IpAddress: "10.0.0.1", ValidatedAt: now.Add(-time.Hour)},
+	} {
+		require.NoError(t, store.StoreApiUsage(usage))
+	}
+
+	purge := usecase.NewApiKeyPurge(store, usecase.RetentionPolicy{RawUsage: 24 * time.Hour, HourlyUsage: 7 * 24 * time.Hour}, usecase.NewRateLimiter())
+	require.NoError(t, purge.PruneApiUsages(context.Background(), now))
+
+	usages, err := store.GetApiUsages("key-1")
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	hours, err := store.GetApiUsageRollups("key-1", domain.UsageGranularityHour)
+	require.NoError(t, err)
+	require.Len(t, hours, 2)
+
+	// Usage stats come from the daily rollups, which are kept
+	apiKey, err := usecase.NewApiKeyListing(store).GetApiKey(context.Background(), "key-1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), apiKey.UsageStats.TotalRequests)
+	require.Equal(t, 2, apiKey.UsageStats.UniqueIPCount)
+	require.Equal(t, "10.0.0.1", apiKey.UsageStats.MostRecentIP)
+}
@@ -0,0 +1,28 @@
+//go:build e2e
+
+package test
+
+import (
+	"testing"
+
+	"github.com/csherida/api-key-manager-service/internal/service/config"
+	"github.com/csherida/api-key-manager-service/internal/service/di"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurableStoresRequireAPepper(t *testing.T) {
+	cfg := config.Default()
+	policy, err := di.NewSecretPolicy(cfg)
+	require.NoError(t, err)
+	require.Len(t, policy.Pepper, 32)
+
+	// Opaque key hashes in the file store must still verify after a restart
+	cfg.Storage.Driver = config.StorageDriverFile
+	_, err = di.NewSecretPolicy(cfg)
+	require.Error(t, err)
+
+	cfg.Secrets.Pepper = "pepper"
+	policy, err = di.NewSecretPolicy(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pepper"), policy.Pepper)
+}
@@ -0,0 +1,86 @@
+//go:build e2e
+
+package test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/domain"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/infra"
+	"github.com/csherida/api-key-manager-service/internal/api-key-manager-service/usecase"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonceCacheKeepsLiveNonces(t *testing.T) {
+	store := infra.NewDataStore()
+	apiKey := storeSigningKey(t, store, "key-1", domain.ApiKeyStatusActive)
+	validate := newSignedValidator(t, store, usecase.ValidationPolicy{NonceCacheSize: 2})
+
+	// Signers without a key are refused before their nonce takes up room
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	require.Error(t, validate(hex.EncodeToString(crypto.FromECDSA(otherKey)), "forged", time.Now()))
+
+	// Timestamps arrive out of order, the nonce recorded first is not the first to expire
+	now := time.Now()
+	require.NoError(t, validate(apiKey, "nonce-1", now))
+	require.NoError(t, validate(apiKey, "nonce-2", now.Add(-30*time.Second)))
+
+	// A full cache refuses new nonces rather than forgetting a live one that could then be replayed
+	require.ErrorIs(t, validate(apiKey, "nonce-3", now), domain.ErrNonceCacheFull)
+	require.ErrorIs(t, validate(apiKey, "nonce-1", now), domain.ErrReplayedRequest)
+	require.ErrorIs(t, validate(apiKey, "nonce-2", now.Add(-30*time.Second)), domain.ErrReplayedRequest)
+}
+
+func TestNoncesOnlyForActiveKeys(t *testing.T) {
+	store := infra.NewDataStore()
+	revokedKey := storeSigningKey(t, store, "revoked", domain.ApiKeyStatusRevoked)
+	busyKey := storeSigningKey(t, store, "busy", domain.ApiKeyStatusActive)
+	quietKey := storeSigningKey(t, store, "quiet", domain.ApiKeyStatusActive)
+	validate := newSignedValidator(t, store, usecase.ValidationPolicy{NonceCacheSize: 2, NoncesPerKey: 1})
+	now := time.Now()
+
+	// A leaked key stays refused once revoked, and its signed requests take no room in the cache
+	for _, nonce := range []string{"nonce-1", "nonce-2", "nonce-3"} {
+		require.ErrorIs(t, validate(revokedKey, nonce, now), domain.ErrApiKeyInactive)
+	}
+
+	// One key cannot take more than its share, so the other keys still get theirs
+	require.NoError(t, validate(busyKey, "nonce-1", now))
+	require.ErrorIs(t, validate(busyKey, "nonce-2", now), domain.ErrNonceLimitReached)
+	require.NoError(t, validate(quietKey, "nonce-1", now))
+}
+
+// storeSigningKey stores a secp256k1 API key with status and returns the key to sign requests with
+func storeSigningKey(t *testing.T, store *infra.DataStore, apiId string, status string) string {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	require.NoError(t, store.StoreApiKey(&domain.ApiKey{
+		ApiId:        apiId,
+		Address:      crypto.PubkeyToAddress(privateKey.PublicKey).Hex(),
+		KeyAlgorithm: domain.KeyAlgorithmSecp256k1,
+		Status:       status,
+	}))
+	return hex.EncodeToString(crypto.FromECDSA(privateKey))
+}
+
+// newSignedValidator returns a function validating a request signed with key, nonce and signedAt under
+// policy, which allows multiple IPs and a one minute time gap
+func newSignedValidator(t *testing.T, store *infra.DataStore, policy usecase.ValidationPolicy) func(key string, nonce string, signedAt time.Time) error {
+	policy.AllowMultipleIPs = true
+	policy.AllowedTimeGap = time.Minute
+	validation := usecase.NewApiKeyValidation(store, policy,
+		usecase.NewKeyAlgorithms(usecase.SecretPolicy{}, usecase.KeyFormatPolicy{Prefix: "akm"}), usecase.NewRateLimiter())
+	return func(key string, nonce string, signedAt time.Time) error {
+		request := domain.SignedRequest{Method: "POST", Path: "/keys/validate", Timestamp: signedAt.Unix(), Nonce: nonce}
+		signature, err := usecase.SignRequest(key, request)
+		require.NoError(t, err)
+		request.Signature = signature
+		_, err = validation.ValidateSignedRequest(context.Background(), request, "10.0.0.1", nil)
+		return err
+	}
+}